@@ -0,0 +1,42 @@
+package notifier
+
+import "sync"
+
+// Notifier delivers values on a channel which can be closed at any time, even while a send is pending.
+type Notifier[T any] struct {
+	c       chan T
+	done    chan struct{}
+	sending sync.Mutex
+}
+
+func New[T any]() *Notifier[T] {
+	return &Notifier[T]{
+		c:    make(chan T),
+		done: make(chan struct{}),
+	}
+}
+
+func (n *Notifier[T]) C() <-chan T {
+	return n.c
+}
+
+// Send blocks until t is received or the notifier is closed.
+func (n *Notifier[T]) Send(t T) {
+	n.sending.Lock()
+	defer n.sending.Unlock()
+	select {
+	case <-n.done: // c is closed
+	default:
+		select {
+		case n.c <- t:
+		case <-n.done:
+		}
+	}
+}
+
+func (n *Notifier[T]) Close() {
+	close(n.done) // unblocks a pending send
+	n.sending.Lock()
+	close(n.c)
+	n.sending.Unlock()
+}
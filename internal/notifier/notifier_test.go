@@ -0,0 +1,32 @@
+package notifier_test
+
+import (
+	"testing"
+	"testing/synctest"
+
+	"github.com/ddirect/container/internal/notifier"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Notifier(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		n := notifier.New[int]()
+		go n.Send(1)
+		assert.Equal(t, 1, <-n.C())
+
+		sent := false
+		go func() {
+			n.Send(2)
+			sent = true
+		}()
+		synctest.Wait()
+		assert.False(t, sent)
+		n.Close()
+		synctest.Wait()
+		assert.True(t, sent)
+		_, ok := <-n.C()
+		assert.False(t, ok)
+
+		n.Send(3) // does not block after Close
+	})
+}
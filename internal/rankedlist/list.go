@@ -8,6 +8,7 @@ import (
 	"slices"
 
 	"github.com/ddirect/container"
+	"github.com/ddirect/container/heap"
 )
 
 type List[R container.Comparer[R], T any] struct {
@@ -16,12 +17,16 @@ type List[R container.Comparer[R], T any] struct {
 }
 
 func New[R container.Comparer[R], T any]() *List[R, T] {
+	return &List[R, T]{
+		seed: newSeed(),
+	}
+}
+
+func newSeed() int {
 	// 32 bit arch: seed >= 1 << 30
 	// 64 bit arch: seed >= 1 << 62
 	const highBit = math.MaxInt>>1 + 1
-	return &List[R, T]{
-		seed: rand.Int() | highBit,
-	}
+	return rand.Int() | highBit
 }
 
 func (h *List[R, T]) Len() int {
@@ -54,6 +59,30 @@ func (h *List[R, T]) RemoveOrdered() iter.Seq[*Item[R, T]] {
 	}
 }
 
+// Ordered returns the items in rank order without removing them. The list must not be modified during the
+// iteration.
+func (h *List[R, T]) Ordered() iter.Seq[*Item[R, T]] {
+	return func(yield func(*Item[R, T]) bool) {
+		if h.Len() == 0 {
+			return
+		}
+		// the frontier holds the indexes of the items whose parent has already been yielded
+		frontier := heap.New(func(a, b uint) bool {
+			return h.s[a].rank.Before(h.s[b].rank)
+		}, nil)
+		frontier.Push(0)
+		for frontier.Len() > 0 {
+			i := frontier.Pop()
+			if !yield(h.s[i]) {
+				return
+			}
+			for c := 2*i + 1; c <= 2*i+2 && c < h.ulen(); c++ {
+				frontier.Push(c)
+			}
+		}
+	}
+}
+
 func (h *List[R, T]) Insert(rank R) *Item[R, T] {
 	item := &Item[R, T]{
 		rank: rank,
@@ -68,6 +97,23 @@ func (h *List[R, T]) First() *Item[R, T] {
 	return h.s[0]
 }
 
+// DueRank returns the rank of the first item, which is the lowest value for which Due yields it.
+func (h *List[R, T]) DueRank() R {
+	return h.First().rank
+}
+
+// Due yields the first item as long as its rank is not after now. The caller must delete each yielded item or move
+// it after now.
+func (h *List[R, T]) Due(now R) iter.Seq[*Item[R, T]] {
+	return func(yield func(*Item[R, T]) bool) {
+		for h.Len() > 0 && !now.Before(h.First().rank) {
+			if !yield(h.First()) {
+				return
+			}
+		}
+	}
+}
+
 func (h *List[R, T]) Random(rnd *rand.Rand) *Item[R, T] {
 	return h.s[rnd.IntN(h.Len())]
 }
@@ -108,6 +154,16 @@ func (h *List[R, T]) SetRank(item *Item[R, T], rank R) {
 	}
 }
 
+// Rerank sets the rank of all the items to the value returned by f, then restores the order.
+func (h *List[R, T]) Rerank(f func(*Item[R, T]) R) {
+	for _, item := range h.s {
+		item.rank = f(item)
+	}
+	for i := h.Len()/2 - 1; i >= 0; i-- {
+		h.down(h.s[i])
+	}
+}
+
 func (h *List[R, T]) index(item *Item[R, T]) uint {
 	return uint(h.seed + item.idx)
 }
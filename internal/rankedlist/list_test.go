@@ -172,3 +172,38 @@ func Test_RemoveOrdered(t *testing.T) {
 
 	assert.Equal(t, 1, h.Len())
 }
+
+func Test_Ordered(t *testing.T) {
+	h := rankedlist.New[int32B, struct{}]()
+	for range 100 {
+		h.Insert(int32B(rand.IntN(50)))
+	}
+	ordered := slices.Collect(h.Ordered())
+	assert.Equal(t, 100, h.Len())
+	assert.Len(t, ordered, 100)
+	assert.True(t, slices.IsSortedFunc(ordered, func(a, b *rankedlist.Item[int32B, struct{}]) int {
+		return cmp.Compare(a.Rank(), b.Rank())
+	}))
+	assert.Equal(t, 100, h.Len())
+
+	for range rankedlist.New[int32B, struct{}]().Ordered() {
+		t.Fatal("empty list yielded an item")
+	}
+}
+
+func Test_Rerank(t *testing.T) {
+	h := rankedlist.New[int32B, struct{}]()
+	for i := range 100 {
+		h.Insert(int32B(i))
+	}
+	h.Rerank(func(item *rankedlist.Item[int32B, struct{}]) int32B {
+		return -item.Rank()
+	})
+	var ranks []int32B
+	for item := range h.RemoveOrdered() {
+		ranks = append(ranks, item.Rank())
+	}
+	assert.Len(t, ranks, 100)
+	assert.True(t, slices.IsSorted(ranks))
+	assert.Equal(t, int32B(-99), ranks[0])
+}
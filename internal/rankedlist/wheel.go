@@ -0,0 +1,302 @@
+package rankedlist
+
+import (
+	"fmt"
+	"iter"
+	"math/bits"
+	"math/rand/v2"
+	"slices"
+
+	"github.com/ddirect/container"
+)
+
+const (
+	wheelBits    = 6
+	wheelSlots   = 1 << wheelBits
+	wheelLevels  = (64 + wheelBits - 1) / wheelBits
+	wheelBuckets = wheelLevels * wheelSlots
+)
+
+// Integer is the constraint of the ranks which can be stored in a Wheel.
+type Integer[R any] interface {
+	container.Comparer[R]
+	~int64
+}
+
+// Wheel is a hierarchical timing wheel. The ranks are divided in ticks of the given granularity; level 0 has one slot
+// per tick, each upper level has slots covering a whole lower level. Inserting, moving and deleting an item is O(1);
+// the items are moved to the lower levels as the wheel turns, which happens in Due. Unlike List, the items are only
+// ordered by slot: First returns any of the items in the first slot.
+type Wheel[R Integer[R], T any] struct {
+	slots       [wheelLevels][wheelSlots][]*Item[R, T]
+	occupied    [wheelLevels]uint64 // one bit per non-empty slot
+	cursor      int64               // current tick; the items before it are kept in its slot
+	granularity int64
+	n           int
+	seed        int
+}
+
+func NewWheel[R Integer[R], T any](granularity R) *Wheel[R, T] {
+	if granularity < 1 {
+		panic(fmt.Errorf("invalid wheel granularity %d", int64(granularity)))
+	}
+	return &Wheel[R, T]{
+		granularity: int64(granularity),
+		seed:        newSeed(),
+	}
+}
+
+func (w *Wheel[R, T]) Len() int {
+	return w.n
+}
+
+func (w *Wheel[R, T]) Clear() {
+	for l := range w.slots {
+		for s := range w.slots[l] {
+			b := w.slots[l][s]
+			for _, item := range b {
+				item.setNotPresent()
+			}
+			clear(b)
+			w.slots[l][s] = b[:0]
+		}
+		w.occupied[l] = 0
+	}
+	w.n = 0
+}
+
+func (w *Wheel[R, T]) Insert(rank R) *Item[R, T] {
+	item := &Item[R, T]{
+		rank: rank,
+	}
+	w.place(item)
+	w.n++
+	return item
+}
+
+// First returns one of the items in the first slot.
+func (w *Wheel[R, T]) First() *Item[R, T] {
+	l, s := w.first()
+	b := w.slots[l][s]
+	return b[len(b)-1]
+}
+
+func (w *Wheel[R, T]) Random(rnd *rand.Rand) *Item[R, T] {
+	i := rnd.IntN(w.Len())
+	for item := range w.All() {
+		if i == 0 {
+			return item
+		}
+		i--
+	}
+	panic("unreachable")
+}
+
+func (w *Wheel[R, T]) All() iter.Seq[*Item[R, T]] {
+	return func(yield func(*Item[R, T]) bool) {
+		for l := range w.slots {
+			for s := range w.slots[l] {
+				for _, item := range w.slots[l][s] {
+					if !yield(item) {
+						return
+					}
+				}
+			}
+		}
+	}
+}
+
+// Ordered returns the items in rank order without removing them. The wheel must not be modified during the
+// iteration. Unlike List.Ordered, it sorts a copy of all the items.
+func (w *Wheel[R, T]) Ordered() iter.Seq[*Item[R, T]] {
+	return func(yield func(*Item[R, T]) bool) {
+		items := slices.SortedFunc(w.All(), func(a, b *Item[R, T]) int {
+			switch {
+			case a.rank.Before(b.rank):
+				return -1
+			case b.rank.Before(a.rank):
+				return 1
+			default:
+				return 0
+			}
+		})
+		for _, item := range items {
+			if !yield(item) {
+				return
+			}
+		}
+	}
+}
+
+// DueRank returns the lowest value for which Due yields an item or turns the wheel: the end of the first slot of
+// level 0, or the start of the first slot of the upper levels.
+func (w *Wheel[R, T]) DueRank() R {
+	l, s := w.first()
+	start := w.slotStart(l, s)
+	if l == 0 {
+		start++
+	}
+	return R(start * w.granularity)
+}
+
+// Due turns the wheel up to the tick of now and yields the items of the slots before it, which all have a rank
+// before now. The caller must delete each yielded item or move it after now.
+func (w *Wheel[R, T]) Due(now R) iter.Seq[*Item[R, T]] {
+	return func(yield func(*Item[R, T]) bool) {
+		target := w.tick(now)
+		for {
+			w.advance(target)
+			if w.cursor >= target {
+				return
+			}
+			b := w.slots[0][w.cursor&(wheelSlots-1)]
+			if !yield(b[len(b)-1]) {
+				return
+			}
+		}
+	}
+}
+
+func (w *Wheel[R, T]) Delete(item *Item[R, T]) {
+	w.unlink(item)
+	item.setNotPresent()
+	w.n--
+}
+
+func (w *Wheel[R, T]) SetRank(item *Item[R, T], rank R) {
+	l, s, _ := w.locate(item)
+	item.rank = rank
+	if nl, ns := w.position(w.tick(rank)); nl != l || ns != s {
+		w.unlink(item)
+		w.place(item)
+	}
+}
+
+// Rerank sets the rank of all the items to the value returned by f, then puts them in their new slots.
+func (w *Wheel[R, T]) Rerank(f func(*Item[R, T]) R) {
+	for _, item := range w.detach() {
+		item.rank = f(item)
+		w.place(item)
+	}
+}
+
+// SetGranularity changes the width of the ticks, then puts all the items in their new slots. The cursor keeps its
+// time, rounded down to the new granularity.
+func (w *Wheel[R, T]) SetGranularity(granularity R) {
+	if granularity < 1 {
+		panic(fmt.Errorf("invalid wheel granularity %d", int64(granularity)))
+	}
+	items := w.detach()
+	w.cursor = w.cursor * w.granularity / int64(granularity)
+	w.granularity = int64(granularity)
+	for _, item := range items {
+		w.place(item)
+	}
+}
+
+// detach empties the slots, returning the items which were in them; they must all be placed again.
+func (w *Wheel[R, T]) detach() []*Item[R, T] {
+	items := slices.Collect(w.All())
+	for l := range w.slots {
+		for s := range w.slots[l] {
+			clear(w.slots[l][s])
+			w.slots[l][s] = w.slots[l][s][:0]
+		}
+		w.occupied[l] = 0
+	}
+	return items
+}
+
+func (w *Wheel[R, T]) tick(rank R) int64 {
+	return int64(rank) / w.granularity
+}
+
+// position returns the slot of an item: the level is given by the highest bit group where the tick differs from
+// the cursor, so that each upper level slot is moved as a whole when the cursor reaches it.
+func (w *Wheel[R, T]) position(tick int64) (l, s int) {
+	tick = max(tick, w.cursor)
+	if x := uint64(tick ^ w.cursor); x != 0 {
+		l = (bits.Len64(x) - 1) / wheelBits
+	}
+	s = int(uint64(tick)>>(l*wheelBits)) & (wheelSlots - 1)
+	return
+}
+
+// slotStart returns the first tick of a slot.
+func (w *Wheel[R, T]) slotStart(l, s int) int64 {
+	var base int64
+	if shift := (l + 1) * wheelBits; shift < 64 {
+		base = w.cursor >> shift << shift
+	}
+	return base | int64(s)<<(l*wheelBits)
+}
+
+// first returns the first non-empty slot: all the slots of a level come before the ones of the upper levels, and
+// within a level they are all after the cursor.
+func (w *Wheel[R, T]) first() (l, s int) {
+	for l, occupied := range w.occupied {
+		if occupied != 0 {
+			return l, bits.TrailingZeros64(occupied)
+		}
+	}
+	return 0, 0
+}
+
+// advance moves the cursor up to target, stopping at the first non-empty slot of level 0. Upper level slots reached
+// on the way are moved to the lower levels.
+func (w *Wheel[R, T]) advance(target int64) {
+	for {
+		l, s := w.first()
+		start := w.slotStart(l, s)
+		if w.occupied[l] == 0 || start > target {
+			w.cursor = max(w.cursor, target)
+			return
+		}
+		w.cursor = max(w.cursor, start)
+		if l == 0 {
+			return
+		}
+		items := w.slots[l][s]
+		w.slots[l][s] = items[:0]
+		w.occupied[l] &^= 1 << s
+		for _, item := range items {
+			w.place(item)
+		}
+		clear(items)
+	}
+}
+
+func (w *Wheel[R, T]) place(item *Item[R, T]) {
+	l, s := w.position(w.tick(item.rank))
+	b := &w.slots[l][s]
+	item.idx = len(*b)*wheelBuckets + l*wheelSlots + s - w.seed
+	*b = append(*b, item)
+	w.occupied[l] |= 1 << s
+}
+
+func (w *Wheel[R, T]) unlink(item *Item[R, T]) {
+	l, s, pos := w.locate(item)
+	b := &w.slots[l][s]
+	n := len(*b) - 1
+	if pos != n {
+		last := (*b)[n]
+		last.idx = item.idx
+		(*b)[pos] = last
+	}
+	(*b)[n] = nil
+	*b = (*b)[:n]
+	if n == 0 {
+		w.occupied[l] &^= 1 << s
+	}
+}
+
+// locate decodes the index of the item, which holds its slot and its position in the slot.
+func (w *Wheel[R, T]) locate(item *Item[R, T]) (l, s, pos int) {
+	i := uint(w.seed + item.idx)
+	b := int(i % wheelBuckets)
+	l, s = b/wheelSlots, b%wheelSlots
+	if i/wheelBuckets >= uint(len(w.slots[l][s])) || w.slots[l][s][i/wheelBuckets] != item {
+		panic(fmt.Errorf("item with index %d not in the wheel", int(i)))
+	}
+	return l, s, int(i / wheelBuckets)
+}
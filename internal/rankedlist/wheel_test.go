@@ -0,0 +1,143 @@
+package rankedlist_test
+
+import (
+	"cmp"
+	"math/rand/v2"
+	"slices"
+	"testing"
+
+	"github.com/ddirect/container/internal/rankedlist"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type int64B int64
+
+func (a int64B) Before(b int64B) bool {
+	return a < b
+}
+
+func Test_WheelDue(t *testing.T) {
+	const granularity = 10
+	rnd := rand.New(rand.NewPCG(1, 0))
+	w := rankedlist.NewWheel[int64B, int](granularity)
+	ref := make(map[*rankedlist.Item[int64B, int]]bool)
+
+	now := int64B(0)
+	for range 200 {
+		for range rnd.IntN(50) {
+			switch {
+			case len(ref) > 0 && rnd.IntN(4) == 0:
+				item := w.Random(rnd)
+				w.Delete(item)
+				assert.False(t, item.Present())
+				delete(ref, item)
+			case len(ref) > 0 && rnd.IntN(4) == 0:
+				w.SetRank(w.Random(rnd), now+int64B(rnd.IntN(100_000)))
+			default:
+				ref[w.Insert(now+int64B(rnd.IntN(100_000)))] = true
+			}
+		}
+		require.Equal(t, len(ref), w.Len())
+		if w.Len() > 0 {
+			assert.LessOrEqual(t, w.DueRank(), slices.Collect(w.Ordered())[0].Rank()+granularity)
+		}
+
+		now += int64B(rnd.IntN(2_000))
+		for item := range w.Due(now) {
+			assert.True(t, item.Rank() < now)
+			w.Delete(item)
+			delete(ref, item)
+		}
+		// only the items in the slot of now can be due
+		for item := range ref {
+			assert.True(t, item.Present())
+			assert.GreaterOrEqual(t, item.Rank()/granularity, now/granularity)
+		}
+	}
+
+	ordered := slices.Collect(w.Ordered())
+	assert.Len(t, ordered, len(ref))
+	assert.True(t, slices.IsSortedFunc(ordered, func(a, b *rankedlist.Item[int64B, int]) int {
+		return cmp.Compare(a.Rank(), b.Rank())
+	}))
+
+	// the items moved before the cursor are due with its slot
+	w.Rerank(func(item *rankedlist.Item[int64B, int]) int64B {
+		return item.Rank() - 50_000
+	})
+	now += granularity
+	for item := range w.Due(now) {
+		w.Delete(item)
+		delete(ref, item)
+	}
+	for item := range ref {
+		assert.GreaterOrEqual(t, item.Rank()/granularity, now/granularity)
+	}
+
+	w.Clear()
+	assert.Zero(t, w.Len())
+	for item := range ref {
+		assert.False(t, item.Present())
+	}
+}
+
+func Test_WheelFirst(t *testing.T) {
+	w := rankedlist.NewWheel[int64B, struct{}](10)
+	w.Insert(1000)
+	w.Insert(105)
+	w.Insert(101)
+	w.Insert(5000)
+	assert.Equal(t, int64B(10), w.First().Rank()/10)
+	assert.Equal(t, int64B(110), w.DueRank())
+
+	for item := range w.Due(1000) {
+		assert.Less(t, item.Rank(), int64B(1000))
+		w.Delete(item)
+	}
+	assert.Equal(t, 2, w.Len())
+	assert.Equal(t, int64B(1000), w.First().Rank())
+	assert.Equal(t, int64B(1010), w.DueRank())
+}
+
+func Test_WheelCrossItemUse(t *testing.T) {
+	w1 := rankedlist.NewWheel[int64B, struct{}](10)
+	w2 := rankedlist.NewWheel[int64B, struct{}](10)
+	item1 := w1.Insert(1)
+	item2 := w2.Insert(1)
+	assert.Panics(t, func() { w1.Delete(item2) })
+	assert.Panics(t, func() { w2.SetRank(item1, 2) })
+	w1.Delete(item1)
+	assert.Panics(t, func() { w1.Delete(item1) })
+}
+
+func Test_WheelSetGranularity(t *testing.T) {
+	rnd := rand.New(rand.NewPCG(1, 0))
+	w := rankedlist.NewWheel[int64B, int](10)
+	ref := make(map[*rankedlist.Item[int64B, int]]bool)
+	for range 1000 {
+		ref[w.Insert(int64B(rnd.IntN(100_000)))] = true
+	}
+	now := int64B(0)
+	for _, granularity := range []int64B{10, 3, 1000, 7} {
+		w.SetGranularity(granularity)
+		require.Equal(t, len(ref), w.Len())
+		for range 5 {
+			now += int64B(rnd.IntN(5_000))
+			for item := range w.Due(now) {
+				assert.True(t, item.Rank() < now)
+				w.Delete(item)
+				delete(ref, item)
+			}
+			for item := range ref {
+				assert.GreaterOrEqual(t, item.Rank()/granularity, now/granularity)
+			}
+		}
+	}
+	for item := range w.Due(200_000) {
+		w.Delete(item)
+		delete(ref, item)
+	}
+	assert.Empty(t, ref)
+	assert.Zero(t, w.Len())
+}
@@ -1,6 +1,9 @@
 package rankedmap
 
 import (
+	"iter"
+	"math/rand/v2"
+
 	"github.com/ddirect/container"
 	"github.com/ddirect/container/internal/rankedlist"
 )
@@ -10,9 +13,24 @@ type kv[K comparable, V any] struct {
 	value V
 }
 
-type rankedList[K comparable, R container.Comparer[R], V any] = rankedlist.List[R, kv[K, V]]
 type rankedItem[K comparable, R container.Comparer[R], V any] = rankedlist.Item[R, kv[K, V]]
 
+// rankedList is implemented by rankedlist.List and rankedlist.Wheel.
+type rankedList[K comparable, R container.Comparer[R], V any] interface {
+	Len() int
+	Clear()
+	Insert(rank R) *rankedItem[K, R, V]
+	First() *rankedItem[K, R, V]
+	Random(rnd *rand.Rand) *rankedItem[K, R, V]
+	All() iter.Seq[*rankedItem[K, R, V]]
+	Ordered() iter.Seq[*rankedItem[K, R, V]]
+	DueRank() R
+	Due(now R) iter.Seq[*rankedItem[K, R, V]]
+	Delete(item *rankedItem[K, R, V])
+	SetRank(item *rankedItem[K, R, V], rank R)
+	Rerank(f func(*rankedItem[K, R, V]) R)
+}
+
 type MapItem[K comparable, R container.Comparer[R], V any] struct {
 	*rankedItem[K, R, V]
 }
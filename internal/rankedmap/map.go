@@ -9,7 +9,7 @@ import (
 )
 
 type Map[K comparable, R container.Comparer[R], V any] struct {
-	r *rankedList[K, R, V]
+	r rankedList[K, R, V]
 	m map[K]*rankedItem[K, R, V]
 }
 
@@ -20,6 +20,14 @@ func New[K comparable, R container.Comparer[R], V any]() *Map[K, R, V] {
 	}
 }
 
+// NewWheel creates a map ordered by a rankedlist.Wheel: First, DueRank and Due follow its approximate order.
+func NewWheel[K comparable, R rankedlist.Integer[R], V any](granularity R) *Map[K, R, V] {
+	return &Map[K, R, V]{
+		r: rankedlist.NewWheel[R, kv[K, V]](granularity),
+		m: make(map[K]*rankedItem[K, R, V]),
+	}
+}
+
 func (m *Map[K, R, V]) Len() int {
 	return m.r.Len()
 }
@@ -79,6 +87,35 @@ func (m *Map[K, R, V]) All() iter.Seq[MapItem[K, R, V]] {
 	}
 }
 
+// Ordered returns the items in rank order without removing them. The map must not be modified during the
+// iteration.
+func (m *Map[K, R, V]) Ordered() iter.Seq[MapItem[K, R, V]] {
+	return func(yield func(MapItem[K, R, V]) bool) {
+		for it := range m.r.Ordered() {
+			if !yield(mapItem(it)) {
+				return
+			}
+		}
+	}
+}
+
+// DueRank returns the lowest value for which Due yields an item; the map must not be empty.
+func (m *Map[K, R, V]) DueRank() R {
+	return m.r.DueRank()
+}
+
+// Due yields the items whose rank is not after now; with a wheel, only the ones in the slots before the slot of now.
+// The caller must delete each yielded item or move it after now.
+func (m *Map[K, R, V]) Due(now R) iter.Seq[MapItem[K, R, V]] {
+	return func(yield func(MapItem[K, R, V]) bool) {
+		for it := range m.r.Due(now) {
+			if !yield(mapItem(it)) {
+				return
+			}
+		}
+	}
+}
+
 func (m *Map[K, R, V]) RemoveOrdered() iter.Seq[MapItem[K, R, V]] {
 	return func(yield func(MapItem[K, R, V]) bool) {
 		for m.Len() > 0 {
@@ -97,6 +134,18 @@ func (m *Map[K, R, V]) SetRank(it MapItem[K, R, V], rank R) {
 	m.r.SetRank(it.rankedItem, rank)
 }
 
+// Rerank sets the rank of all the items to the value returned by f, then restores the order.
+func (m *Map[K, R, V]) Rerank(f func(MapItem[K, R, V]) R) {
+	m.r.Rerank(func(item *rankedItem[K, R, V]) R {
+		return f(mapItem(item))
+	})
+}
+
+// SetGranularity changes the granularity of a map created with NewWheel.
+func (m *Map[K, R, V]) SetGranularity(granularity R) {
+	m.r.(interface{ SetGranularity(R) }).SetGranularity(granularity)
+}
+
 func (m *Map[K, R, V]) Delete(it MapItem[K, R, V]) {
 	m.deleteItem(it.rankedItem)
 }
@@ -52,11 +52,14 @@ func Test_Basic(t *testing.T) {
 	s2 := slices.SortedFunc(toRefItems(t, m.All()), cmpRankThenKey)
 
 	// use native sorting for ranks and then only sort by key if the rank is the same
+	s4 := slices.Collect(toRefItems(t, m.Ordered()))
+	slices.SortStableFunc(s4, cmpOnlyKeyIfRankSame)
 	s3 := slices.Collect(toRefItems(t, m.RemoveOrdered()))
 	slices.SortStableFunc(s3, cmpOnlyKeyIfRankSame)
 
 	assert.Equal(t, s1, s2)
 	assert.Equal(t, s1, s3)
+	assert.Equal(t, s1, s4)
 	assert.Equal(t, 0, m.Len())
 }
 
@@ -172,6 +175,44 @@ func Test_RemoveOrdered(t *testing.T) {
 	assert.Equal(t, 1, m.Len())
 }
 
+type int64B int64
+
+func (a int64B) Before(b int64B) bool {
+	return a < b
+}
+
+func Test_Due(t *testing.T) {
+	for _, m := range []*rankedmap.Map[int, int64B, string]{
+		rankedmap.New[int, int64B, string](),
+		rankedmap.NewWheel[int, int64B, string](10),
+	} {
+		for k := range 100 {
+			m.Set(k, int64B(k*10+5), "")
+		}
+		assert.LessOrEqual(t, m.DueRank(), int64B(10))
+
+		var keys []int
+		for item := range m.Due(500) {
+			keys = append(keys, item.Key())
+			if item.Key()%2 == 0 {
+				m.Delete(item)
+			} else {
+				m.SetRank(item, 2000)
+			}
+		}
+		slices.Sort(keys)
+		want := make([]int, 50)
+		for k := range want {
+			want[k] = k
+		}
+		assert.Equal(t, want, keys)
+		assert.Equal(t, 75, m.Len())
+		for item := range m.All() {
+			assert.Greater(t, item.Rank(), int64B(500))
+		}
+	}
+}
+
 func makeCore(log LogFunc) func(t *testing.T, seed uint64, variance int) {
 	type (
 		K int32
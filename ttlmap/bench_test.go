@@ -0,0 +1,63 @@
+package ttlmap_test
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"testing"
+	"time"
+
+	"github.com/ddirect/container/ttlmap"
+)
+
+// Benchmark_Backend compares the heap and the timing wheel across map sizes and touch rates. Each operation advances
+// the clock by ttl/size, so that the map stays at about the given size, then it either touches a random key or
+// sets a new one; the expired items are removed every 64 operations.
+func Benchmark_Backend(b *testing.B) {
+	const (
+		ttl      = time.Minute
+		accuracy = time.Second
+	)
+	backends := []struct {
+		name string
+		opts []ttlmap.Option
+	}{
+		{"heap", nil},
+		{"wheel", []ttlmap.Option{ttlmap.WithTimingWheel()}},
+	}
+	for _, backend := range backends {
+		for _, size := range []int{1_000, 100_000, 1_000_000} {
+			for _, touchRate := range []int{0, 50, 90} {
+				b.Run(fmt.Sprintf("%s/size=%d/touch=%d%%", backend.name, size, touchRate), func(b *testing.B) {
+					c := ttlmap.NewManualClock(time.Unix(1000, 0))
+					m := ttlmap.NewManual[int, int](ttl, accuracy, append(backend.opts, ttlmap.WithClock(c))...)
+					step := ttl / time.Duration(size)
+					rnd := rand.New(rand.NewPCG(1, 0))
+					next := 0
+					expire := func() {
+						for range m.Expire(c.Now()) {
+						}
+					}
+					for range size {
+						c.Advance(step)
+						m.Set(next, next)
+						next++
+					}
+					expire()
+
+					for i := 0; b.Loop(); i++ {
+						c.Advance(step)
+						if rnd.IntN(100) < touchRate {
+							m.Get(next - 1 - rnd.IntN(size))
+						} else {
+							m.Set(next, next)
+							next++
+						}
+						if i%64 == 0 {
+							expire()
+						}
+					}
+				})
+			}
+		}
+	}
+}
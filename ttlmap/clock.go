@@ -0,0 +1,140 @@
+package ttlmap
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock is the source of time used by a Map. The default clock uses time.Now and time.AfterFunc.
+type Clock interface {
+	Now() time.Time
+	// AfterFunc waits for the duration to elapse and then calls f in its own goroutine.
+	AfterFunc(d time.Duration, f func()) Timer
+}
+
+// Timer is the handle returned by Clock.AfterFunc; Stop and Reset behave like the time.Timer methods.
+type Timer interface {
+	Stop() bool
+	Reset(d time.Duration) bool
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) AfterFunc(d time.Duration, f func()) Timer {
+	return time.AfterFunc(d, f)
+}
+
+// ManualClock is a Clock whose time only moves when Advance or AdvanceToNext are called. Timer functions are
+// called synchronously by the goroutine advancing the clock, in deadline order, with the clock set to their
+// deadline. It is safe for concurrent use.
+type ManualClock struct {
+	mutex  sync.Mutex
+	now    time.Time
+	timers []*manualTimer
+}
+
+type manualTimer struct {
+	c    *ManualClock
+	when time.Time
+	f    func()
+}
+
+func NewManualClock(now time.Time) *ManualClock {
+	return &ManualClock{now: now}
+}
+
+func (c *ManualClock) Now() time.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.now
+}
+
+func (c *ManualClock) AfterFunc(d time.Duration, f func()) Timer {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	t := &manualTimer{c: c, f: f}
+	c.arm(t, d)
+	return t
+}
+
+// Advance moves the clock forward by d, firing all the timers expiring in the meantime.
+func (c *ManualClock) Advance(d time.Duration) {
+	c.mutex.Lock()
+	end := c.now.Add(d)
+	c.mutex.Unlock()
+	for c.fireNext(end) {
+	}
+	c.mutex.Lock()
+	if c.now.Before(end) {
+		c.now = end
+	}
+	c.mutex.Unlock()
+}
+
+// AdvanceToNext moves the clock to the deadline of the earliest timer and fires it. It returns false when no
+// timer is pending.
+func (c *ManualClock) AdvanceToNext() bool {
+	return c.fireNext(time.Time{})
+}
+
+// Pending returns the number of timers which have not fired or been stopped yet.
+func (c *ManualClock) Pending() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return len(c.timers)
+}
+
+// fireNext fires the earliest timer expiring not after end; a zero end means no limit.
+func (c *ManualClock) fireNext(end time.Time) bool {
+	c.mutex.Lock()
+	var next *manualTimer
+	for _, t := range c.timers {
+		if next == nil || t.when.Before(next.when) {
+			next = t
+		}
+	}
+	if next == nil || (!end.IsZero() && next.when.After(end)) {
+		c.mutex.Unlock()
+		return false
+	}
+	c.disarm(next)
+	if c.now.Before(next.when) {
+		c.now = next.when
+	}
+	c.mutex.Unlock()
+	next.f()
+	return true
+}
+
+func (c *ManualClock) arm(t *manualTimer, d time.Duration) bool {
+	active := c.disarm(t)
+	t.when = c.now.Add(d)
+	c.timers = append(c.timers, t)
+	return active
+}
+
+func (c *ManualClock) disarm(t *manualTimer) bool {
+	for i, x := range c.timers {
+		if x == t {
+			c.timers = append(c.timers[:i], c.timers[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+func (t *manualTimer) Stop() bool {
+	t.c.mutex.Lock()
+	defer t.c.mutex.Unlock()
+	return t.c.disarm(t)
+}
+
+func (t *manualTimer) Reset(d time.Duration) bool {
+	t.c.mutex.Lock()
+	defer t.c.mutex.Unlock()
+	return t.c.arm(t, d)
+}
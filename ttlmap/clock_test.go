@@ -0,0 +1,86 @@
+package ttlmap_test
+
+import (
+	"iter"
+	"testing"
+	"time"
+
+	"github.com/ddirect/container/ttlmap"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ManualClock(t *testing.T) {
+	t0 := time.Unix(1000, 0)
+	c := ttlmap.NewManualClock(t0)
+
+	var fired []string
+	fire := func(name string) func() {
+		return func() {
+			fired = append(fired, name)
+		}
+	}
+	assertFired := func(at time.Duration, names ...string) {
+		assert.Equal(t, names, fired)
+		assert.Equal(t, t0.Add(at), c.Now())
+	}
+
+	c.AfterFunc(3*time.Second, fire("a"))
+	tb := c.AfterFunc(time.Second, fire("b"))
+	tc := c.AfterFunc(2*time.Second, fire("c"))
+	c.AfterFunc(5*time.Second, fire("d"))
+	assert.Equal(t, 4, c.Pending())
+
+	assert.True(t, tc.Stop())
+	assert.False(t, tc.Stop())
+	assert.True(t, tb.Reset(4*time.Second))
+
+	c.Advance(3500 * time.Millisecond)
+	assertFired(3500*time.Millisecond, "a")
+
+	assert.True(t, c.AdvanceToNext())
+	assertFired(4*time.Second, "a", "b")
+	assert.False(t, tb.Stop())
+
+	assert.False(t, tc.Reset(5*time.Second))
+	assert.True(t, c.AdvanceToNext())
+	assertFired(5*time.Second, "a", "b", "d")
+	assert.True(t, c.AdvanceToNext())
+	assertFired(9*time.Second, "a", "b", "d", "c")
+	assert.False(t, c.AdvanceToNext())
+	assert.Zero(t, c.Pending())
+}
+
+func Test_MapWithManualClock(t *testing.T) {
+	const (
+		ttl      = time.Second
+		accuracy = ttl / 10
+	)
+	c := ttlmap.NewManualClock(time.Unix(1000, 0))
+	var expired []int
+	m := ttlmap.NewAsync(ttl, accuracy, func(items iter.Seq[ttlmap.Item[int, time.Time]]) {
+		for item := range items {
+			elapsed := c.Now().Sub(*item.Value())
+			assert.GreaterOrEqual(t, elapsed, ttl)
+			assert.LessOrEqual(t, elapsed, ttl+accuracy)
+			expired = append(expired, item.Key())
+		}
+	}, ttlmap.WithClock(c))
+
+	m.Set(0, c.Now())
+	c.Advance(accuracy)
+	m.Set(1, c.Now())
+	assert.Equal(t, 1, c.Pending())
+
+	c.Advance(ttl - accuracy)
+	assert.Empty(t, expired)
+	assert.True(t, c.AdvanceToNext())
+	assert.Equal(t, []int{0}, expired)
+
+	item := m.GetNoTouch(1)
+	m.Touch(item)
+	*item.Value() = c.Now()
+	c.Advance(ttl + accuracy)
+	assert.Equal(t, []int{0, 1}, expired)
+	assert.Zero(t, m.Len())
+	assert.Zero(t, c.Pending())
+}
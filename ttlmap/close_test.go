@@ -0,0 +1,82 @@
+package ttlmap_test
+
+import (
+	"iter"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	"github.com/ddirect/container/ttlmap"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Close(t *testing.T) {
+	const ttl = time.Second
+	for _, flush := range []bool{false, true} {
+		c := ttlmap.NewManualClock(time.Unix(1000, 0))
+		var expired []int
+		m := ttlmap.NewAsync(ttl, 0, func(items iter.Seq[ttlmap.Item[int, int]]) {
+			for item := range items {
+				expired = append(expired, item.Key())
+			}
+		}, ttlmap.WithClock(c))
+
+		m.Set(0, 0)
+		m.Set(1, 1)
+		m.Close(flush)
+		if flush {
+			assert.Equal(t, 1, c.Pending())
+			c.Advance(0)
+			assert.ElementsMatch(t, []int{0, 1}, expired)
+			assert.Zero(t, m.Len())
+		} else {
+			assert.Empty(t, expired)
+			assert.Equal(t, 2, m.Len())
+			assert.True(t, m.GetNoTouch(0).Present())
+		}
+		assert.Zero(t, c.Pending())
+
+		item := m.GetNoTouch(1)
+		assert.PanicsWithValue(t, ttlmap.ErrClosed, func() { m.Set(2, 2) })
+		assert.PanicsWithValue(t, ttlmap.ErrClosed, func() { m.GetOrCreate(2) })
+		assert.PanicsWithValue(t, ttlmap.ErrClosed, func() { m.Get(0) })
+		assert.PanicsWithValue(t, ttlmap.ErrClosed, func() { m.Touch(item) })
+		assert.PanicsWithValue(t, ttlmap.ErrClosed, func() { m.DeleteKey(0) })
+		assert.PanicsWithValue(t, ttlmap.ErrClosed, func() { m.Clear() })
+		assert.PanicsWithValue(t, ttlmap.ErrClosed, func() { m.Close(flush) })
+	}
+}
+
+func Test_CloseChannel(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		const ttl = time.Second
+
+		m, expired := ttlmap.New[int, int](ttl, 0)
+		m.Set(0, 0)
+		time.Sleep(ttl / 2)
+		m.Set(1, 1)
+		m.Set(2, 2)
+		m.Close(true)
+		var keys []int
+		for seq := range expired {
+			for item := range seq {
+				keys = append(keys, item.Key())
+			}
+		}
+		assert.ElementsMatch(t, []int{0, 1, 2}, keys)
+
+		m, expired = ttlmap.New[int, int](ttl, 0)
+		m.Set(0, 0)
+		time.Sleep(2 * ttl)
+		synctest.Wait()
+		// the timer goroutine is blocked sending the expired items
+		m.Close(false)
+		_, ok := <-expired
+		assert.False(t, ok)
+
+		m, expired = ttlmap.New[int, int](ttl, 0)
+		m.Close(true)
+		_, ok = <-expired
+		assert.False(t, ok)
+	})
+}
@@ -0,0 +1,86 @@
+package ttlmap
+
+import "fmt"
+
+// ComputeResult tells what Compute and its variants did with the item.
+type ComputeResult int
+
+const (
+	ComputeNone     ComputeResult = iota // the map was not modified
+	ComputeInserted                      // a new item was inserted
+	ComputeUpdated                       // the value of the item was replaced
+	ComputeDeleted                       // the item was deleted
+)
+
+func (r ComputeResult) String() string {
+	switch r {
+	case ComputeNone:
+		return "none"
+	case ComputeInserted:
+		return "inserted"
+	case ComputeUpdated:
+		return "updated"
+	case ComputeDeleted:
+		return "deleted"
+	default:
+		return fmt.Sprintf("ComputeResult(%d)", int(r))
+	}
+}
+
+// Compute calls f with the value stored under k, if any, and stores the value it returns if keep is true; otherwise
+// the item is deleted, or not inserted. Storing a value works like Set. It returns the item, which is not present if
+// it was deleted or not inserted, and what was done. f must not call any method of the map.
+func (m *Map[K, V]) Compute(k K, f func(old V, exists bool) (v V, keep bool)) (Item[K, V], ComputeResult) {
+	m.checkOpen()
+	now := m.now()
+	if item := wrapItem(m.m.Get(k)); item.Present() {
+		v, keep := f(*item.Value(), true)
+		return m.update(item, v, keep, now)
+	}
+	var zero V
+	v, keep := f(zero, false)
+	return m.insert(k, v, keep, now)
+}
+
+// ComputeIfPresent is like Compute, but f is called only if k is present.
+func (m *Map[K, V]) ComputeIfPresent(k K, f func(old V) (v V, keep bool)) (Item[K, V], ComputeResult) {
+	m.checkOpen()
+	item := wrapItem(m.m.Get(k))
+	if !item.Present() {
+		return item, ComputeNone
+	}
+	v, keep := f(*item.Value())
+	return m.update(item, v, keep, m.now())
+}
+
+// ComputeIfAbsent is like Compute, but f is called only if k is not present; otherwise the item is touched.
+func (m *Map[K, V]) ComputeIfAbsent(k K, f func() (v V, keep bool)) (Item[K, V], ComputeResult) {
+	m.checkOpen()
+	now := m.now()
+	if item := wrapItem(m.m.Get(k)); item.Present() {
+		m.touch(item, now)
+		return item, ComputeNone
+	}
+	v, keep := f()
+	return m.insert(k, v, keep, now)
+}
+
+func (m *Map[K, V]) update(item Item[K, V], v V, keep bool, now timestamp) (Item[K, V], ComputeResult) {
+	if !keep {
+		m.delete(item)
+		return item, ComputeDeleted
+	}
+	m.written(item, true, v, now)
+	m.store(item, true, v)
+	return item, ComputeUpdated
+}
+
+func (m *Map[K, V]) insert(k K, v V, keep bool, now timestamp) (Item[K, V], ComputeResult) {
+	if !keep {
+		return m.NullItem(), ComputeNone
+	}
+	item, _ := m.getOrCreate(k, 0, now)
+	m.written(item, false, v, now)
+	m.store(item, false, v)
+	return item, ComputeInserted
+}
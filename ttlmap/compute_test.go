@@ -0,0 +1,97 @@
+package ttlmap_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ddirect/container/ttlmap"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Compute(t *testing.T) {
+	const ttl = time.Second
+	c := ttlmap.NewManualClock(time.Unix(1000, 0))
+	var removed []ttlmap.RemovalReason
+	m := ttlmap.NewManual[string, int](ttl, 0, ttlmap.WithClock(c),
+		ttlmap.WithRemovalListener(func(k string, v int, reason ttlmap.RemovalReason) {
+			removed = append(removed, reason)
+		}))
+
+	increment := func(old int, exists bool) (int, bool) {
+		return old + 1, old < 2
+	}
+	item, r := m.Compute("a", increment)
+	assert.Equal(t, ttlmap.ComputeInserted, r)
+	assert.Equal(t, 1, *item.Value())
+
+	c.Advance(ttl / 2)
+	item, r = m.Compute("a", increment)
+	assert.Equal(t, ttlmap.ComputeUpdated, r)
+	assert.Equal(t, 2, *item.Value())
+	assert.Equal(t, ttl, m.Remaining(item))
+
+	item, r = m.Compute("a", increment)
+	assert.Equal(t, ttlmap.ComputeDeleted, r)
+	assert.False(t, item.Present())
+	assert.False(t, m.Exists("a"))
+	assert.Equal(t, []ttlmap.RemovalReason{ttlmap.ReasonReplaced, ttlmap.ReasonDeleted}, removed)
+
+	item, r = m.Compute("b", func(old int, exists bool) (int, bool) {
+		assert.False(t, exists)
+		return 0, false
+	})
+	assert.Equal(t, ttlmap.ComputeNone, r)
+	assert.False(t, item.Present())
+	assert.Zero(t, m.Len())
+}
+
+func Test_ComputeIf(t *testing.T) {
+	const ttl = time.Second
+	c := ttlmap.NewManualClock(time.Unix(1000, 0))
+	m := ttlmap.NewManual[string, int](ttl, 0, ttlmap.WithClock(c))
+
+	never := func(int) (int, bool) {
+		t.Fatal("called for a missing key")
+		return 0, false
+	}
+	_, r := m.ComputeIfPresent("a", never)
+	assert.Equal(t, ttlmap.ComputeNone, r)
+
+	item, r := m.ComputeIfAbsent("a", func() (int, bool) { return 1, true })
+	assert.Equal(t, ttlmap.ComputeInserted, r)
+	assert.Equal(t, 1, *item.Value())
+
+	// a present item is touched, but not changed
+	c.Advance(ttl / 2)
+	item, r = m.ComputeIfAbsent("a", func() (int, bool) {
+		t.Fatal("called for a present key")
+		return 0, false
+	})
+	assert.Equal(t, ttlmap.ComputeNone, r)
+	assert.Equal(t, 1, *item.Value())
+	assert.Equal(t, ttl, m.Remaining(item))
+
+	item, r = m.ComputeIfPresent("a", func(old int) (int, bool) { return old * 10, true })
+	assert.Equal(t, ttlmap.ComputeUpdated, r)
+	assert.Equal(t, 10, *item.Value())
+
+	_, r = m.ComputeIfPresent("a", func(old int) (int, bool) { return 0, false })
+	assert.Equal(t, ttlmap.ComputeDeleted, r)
+	assert.Zero(t, m.Len())
+}
+
+func Test_ComputeSync(t *testing.T) {
+	m := ttlmap.NewSharded[string, int](4, time.Second, 0, nil)
+	defer m.Close(false)
+
+	v, r := m.Compute("a", func(old int, exists bool) (int, bool) { return old + 1, true })
+	assert.Equal(t, 1, v)
+	assert.Equal(t, ttlmap.ComputeInserted, r)
+	v, r = m.ComputeIfAbsent("a", func() (int, bool) { return 5, true })
+	assert.Equal(t, 1, v)
+	assert.Equal(t, ttlmap.ComputeNone, r)
+	v, r = m.ComputeIfPresent("a", func(old int) (int, bool) { return old, false })
+	assert.Zero(t, v)
+	assert.Equal(t, ttlmap.ComputeDeleted, r)
+	assert.Equal(t, "deleted", r.String())
+}
@@ -0,0 +1,52 @@
+package ttlmap
+
+import (
+	"cmp"
+	"time"
+)
+
+// Decision tells the map what to do with an expired item delivered to the handler; see Map.Decide. The zero value is
+// the same as Drop().
+type Decision struct {
+	keep bool
+	ttl  timestamp // 0 for the item time-to-live
+}
+
+// Drop removes the item, even if the handler touched it; without a decision, the item is removed unless touched.
+func Drop() Decision {
+	return Decision{}
+}
+
+// Keep keeps the item for its time-to-live, as if it was just written.
+func Keep() Decision {
+	return Decision{keep: true}
+}
+
+// KeepFor keeps the item for ttl, which also becomes its time-to-live as with TouchWithTTL. It is meant for lease
+// renewals and for retrying later with a different delay.
+func KeepFor(ttl time.Duration) Decision {
+	return Decision{keep: true, ttl: itemTTL(ttl)}
+}
+
+// Decide applies the decision to an item while it is delivered to the expired items handler, before moving to the
+// next item: the kept items are not removed, and they are delivered again when their new lifetime expires. Decide
+// bypasses the expire-after-write mode and the expiry policy, but the maximum age still applies, so an item which
+// reached it is removed anyway. The evicted items and the items flushed by Close cannot be kept.
+func (m *Map[K, V]) Decide(item Item[K, V], d Decision) {
+	if m.closed || !item.Present() {
+		return
+	}
+	if !d.keep {
+		// the cleanup removes the items whose rank is not after its time
+		if m.expiring != 0 && m.expiring.Before(item.Rank()) {
+			m.m.SetRank(item.MapItem, m.expiring)
+		}
+		return
+	}
+	now := cmp.Or(m.expiring, m.now())
+	if d.ttl != 0 {
+		m.setTTL(item, d.ttl, now)
+	} else {
+		m.refresh(item, now)
+	}
+}
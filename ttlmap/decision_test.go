@@ -0,0 +1,131 @@
+package ttlmap_test
+
+import (
+	"iter"
+	"testing"
+	"time"
+
+	"github.com/ddirect/container/ttlmap"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Decide(t *testing.T) {
+	const ttl = time.Second
+	start := time.Unix(1000, 0)
+	c := ttlmap.NewManualClock(start)
+	renewals := map[int]int{}
+	var removed []int
+	var m *ttlmap.Map[int, int]
+	m = ttlmap.NewAsync(ttl, 0, func(items iter.Seq[ttlmap.Item[int, int]]) {
+		for item := range items {
+			k := item.Key()
+			switch {
+			case k == 0 && renewals[k] < 2:
+				// lease renewed twice
+				m.Decide(item, ttlmap.Keep())
+			case k == 1 && renewals[k] < 1:
+				// retry later
+				m.Decide(item, ttlmap.KeepFor(ttl/2))
+			default:
+				m.Decide(item, ttlmap.Drop())
+				removed = append(removed, k)
+				continue
+			}
+			renewals[k]++
+		}
+	}, ttlmap.WithClock(c), ttlmap.WithStats())
+	m.Set(0, 0)
+	m.Set(1, 1)
+	m.Set(2, 2)
+
+	c.Advance(ttl)
+	assert.ElementsMatch(t, []int{2}, removed)
+	assert.Equal(t, ttl/2, m.Remaining(m.GetNoTouch(1)))
+	c.Advance(ttl / 2)
+	assert.ElementsMatch(t, []int{2, 1}, removed)
+	c.Advance(ttl / 2)
+	assert.Equal(t, 1, m.Len())
+	c.Advance(ttl)
+	assert.ElementsMatch(t, []int{2, 1, 0}, removed)
+	assert.Equal(t, map[int]int{0: 2, 1: 1}, renewals)
+	assert.Equal(t, uint64(3), m.Stats().Resurrections)
+	assert.Equal(t, uint64(3), m.Stats().Expirations)
+}
+
+func Test_DecideManual(t *testing.T) {
+	const ttl = time.Second
+	start := time.Unix(1000, 0)
+	c := ttlmap.NewManualClock(start)
+	m := ttlmap.NewManual[int, int](ttl, 0, ttlmap.WithClock(c))
+	m.Set(0, 0)
+
+	// the new lifetime starts from the time passed to Expire, not from the clock
+	for item := range m.Expire(start.Add(2 * ttl)) {
+		m.Decide(item, ttlmap.KeepFor(ttl/2))
+	}
+	assert.Equal(t, 1, m.Len())
+	deadline, _ := m.NextDeadline()
+	assert.Equal(t, start.Add(2*ttl+ttl/2), deadline)
+
+	// the flushed items cannot be kept
+	m.Close(true)
+	for item := range m.Expire(c.Now()) {
+		m.Decide(item, ttlmap.Keep())
+	}
+	assert.Zero(t, m.Len())
+}
+
+func Test_DecideEvicted(t *testing.T) {
+	const ttl = time.Second
+	c := ttlmap.NewManualClock(time.Unix(1000, 0))
+	m := ttlmap.NewManual[int, int](ttl, 0, ttlmap.WithClock(c), ttlmap.WithMaxLen(1))
+	m.Set(0, 0)
+	m.Set(1, 1)
+	var evicted []int
+	for item := range m.Expire(c.Now()) {
+		m.Decide(item, ttlmap.Keep())
+		evicted = append(evicted, item.Key())
+	}
+	assert.Equal(t, []int{0}, evicted)
+	assert.False(t, m.Exists(0))
+	assert.Equal(t, 1, m.Len())
+}
+
+func Test_DecideMaxAge(t *testing.T) {
+	const ttl = time.Second
+	c := ttlmap.NewManualClock(time.Unix(1000, 0))
+	m := ttlmap.NewManual[int, int](ttl, 0, ttlmap.WithClock(c), ttlmap.WithMaxAge(ttl))
+	m.Set(0, 0)
+	c.Advance(ttl)
+	for item := range m.Expire(c.Now()) {
+		m.Decide(item, ttlmap.KeepFor(ttl))
+	}
+	assert.Zero(t, m.Len())
+}
+
+func Test_KeepForInvalid(t *testing.T) {
+	assert.Panics(t, func() { ttlmap.KeepFor(0) })
+}
+
+func Test_DecideDropTouched(t *testing.T) {
+	const ttl = time.Second
+	for _, wheel := range []bool{false, true} {
+		c := ttlmap.NewManualClock(time.Unix(1000, 0))
+		opts := []ttlmap.Option{ttlmap.WithClock(c), ttlmap.WithStats()}
+		if wheel {
+			opts = append(opts, ttlmap.WithTimingWheel())
+		}
+		var m *ttlmap.Map[int, int]
+		m = ttlmap.NewAsync(ttl, ttl/10, func(items iter.Seq[ttlmap.Item[int, int]]) {
+			for item := range items {
+				m.Touch(item)
+				m.Decide(item, ttlmap.Drop())
+			}
+		}, opts...)
+		m.Set(0, 0)
+		c.Advance(2 * ttl)
+		assert.Zero(t, m.Len(), "wheel: %v", wheel)
+		assert.Equal(t, uint64(1), m.Stats().Expirations)
+		assert.Zero(t, m.Stats().Resurrections)
+	}
+}
@@ -0,0 +1,94 @@
+package ttlmap_test
+
+import (
+	"fmt"
+	"iter"
+	"math/rand/v2"
+	"testing"
+	"time"
+
+	"github.com/ddirect/container/ttlmap"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ExpireAfterWrite(t *testing.T) {
+	const ttl = time.Second
+	c := ttlmap.NewManualClock(time.Unix(1000, 0))
+	var expired []int
+	m := ttlmap.NewAsync(ttl, 0, func(items iter.Seq[ttlmap.Item[int, int]]) {
+		for item := range items {
+			expired = append(expired, item.Key())
+		}
+	}, ttlmap.WithClock(c), ttlmap.WithExpireAfterWrite())
+
+	m.Set(0, 0)
+	m.Set(1, 1)
+	m.Set(2, 2)
+	c.Advance(ttl / 2)
+	m.Touch(m.Get(0))
+	m.GetOrCreate(1)
+	m.Set(2, 20)
+	c.Advance(ttl / 2)
+	assert.ElementsMatch(t, []int{0, 1}, expired)
+	c.Advance(ttl / 2)
+	assert.ElementsMatch(t, []int{0, 1, 2}, expired)
+}
+
+// Verifies the lifetime bounds documented in the design comment for both expiration modes.
+func Test_ExpireModeAccuracy(t *testing.T) {
+	const (
+		ttl      = time.Second
+		accuracy = ttl / 10
+		keys     = 20
+		ops      = 100000
+	)
+	for _, afterWrite := range []bool{false, true} {
+		t.Run(fmt.Sprintf("afterWrite=%v", afterWrite), func(t *testing.T) {
+			c := ttlmap.NewManualClock(time.Unix(1000, 0))
+			ref := make(map[int]time.Time) // the time since when the lifetime is counted
+			opts := []ttlmap.Option{ttlmap.WithClock(c)}
+			if afterWrite {
+				opts = append(opts, ttlmap.WithExpireAfterWrite())
+			}
+			expired := 0
+			m := ttlmap.NewAsync(ttl, accuracy, func(items iter.Seq[ttlmap.Item[int, int]]) {
+				for item := range items {
+					elapsed := c.Now().Sub(ref[item.Key()])
+					assert.GreaterOrEqual(t, elapsed, ttl)
+					assert.LessOrEqual(t, elapsed, ttl+accuracy)
+					delete(ref, item.Key())
+					expired++
+				}
+			}, opts...)
+
+			rnd := rand.New(rand.NewPCG(1, 2))
+			for range ops {
+				k := rnd.IntN(keys)
+				switch rnd.IntN(4) {
+				case 0:
+					m.Set(k, k)
+					ref[k] = c.Now()
+				case 1:
+					if m.Get(k).Present() && !afterWrite {
+						ref[k] = c.Now()
+					}
+				case 2:
+					if _, found := m.GetOrCreate(k); !found || !afterWrite {
+						ref[k] = c.Now()
+					}
+				case 3:
+					if item := m.GetNoTouch(k); item.Present() {
+						m.Touch(item)
+						if !afterWrite {
+							ref[k] = c.Now()
+						}
+					}
+				}
+				c.Advance(time.Duration(rnd.IntN(int(ttl / 20))))
+			}
+			c.Advance(ttl + accuracy)
+			assert.Empty(t, ref)
+			assert.NotZero(t, expired)
+		})
+	}
+}
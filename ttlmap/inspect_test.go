@@ -0,0 +1,39 @@
+package ttlmap_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ddirect/container/ttlmap"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Inspect(t *testing.T) {
+	const ttl = time.Second
+	start := time.Unix(1000, 0)
+	c := ttlmap.NewManualClock(start)
+	m := ttlmap.NewManual[int, int](ttl, 0, ttlmap.WithClock(c))
+
+	m.Set(0, 0)
+	c.Advance(ttl / 4)
+	m.SetWithTTL(1, 1, ttl/2)
+	m.Set(2, 2)
+	c.Advance(ttl / 4)
+
+	item := m.GetNoTouch(0)
+	assert.Equal(t, start.Add(ttl), m.ExpiresAt(item))
+	assert.Equal(t, ttl/2, m.Remaining(item))
+	item = m.GetNoTouch(1)
+	assert.Equal(t, start.Add(ttl*3/4), m.ExpiresAt(item))
+	assert.Equal(t, ttl/4, m.Remaining(item))
+
+	var keys []int
+	for item := range m.Ordered() {
+		keys = append(keys, item.Key())
+	}
+	assert.Equal(t, []int{1, 0, 2}, keys)
+	assert.Equal(t, 3, m.Len())
+
+	c.Advance(ttl)
+	assert.Zero(t, m.Remaining(m.GetNoTouch(2)))
+}
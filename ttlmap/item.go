@@ -2,4 +2,33 @@ package ttlmap
 
 import "github.com/ddirect/container/internal/rankedmap"
 
-type Item[K comparable, V any] = rankedmap.MapItem[K, timestamp, V]
+type entry[V any] struct {
+	value   V
+	ttl     timestamp // 0 when the map ttl is used
+	weight  int64
+	created timestamp // last write, for the maximum age
+	evicted bool
+}
+
+type Item[K comparable, V any] struct {
+	rankedmap.MapItem[K, timestamp, entry[V]]
+}
+
+func (it Item[K, V]) Value() *V {
+	return &it.MapItem.Value().value
+}
+
+// Evicted reports whether the item was removed to respect the maximum length or weight instead of expiring, either
+// to make room for a new item or because a value grew. Evicted items are delivered to the expired items handler
+// after they have been removed from the map, so they cannot be kept alive by touching them.
+func (it Item[K, V]) Evicted() bool {
+	return it.entry().evicted
+}
+
+func wrapItem[K comparable, V any](item rankedmap.MapItem[K, timestamp, entry[V]]) Item[K, V] {
+	return Item[K, V]{item}
+}
+
+func (it Item[K, V]) entry() *entry[V] {
+	return it.MapItem.Value()
+}
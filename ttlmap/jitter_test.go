@@ -0,0 +1,75 @@
+package ttlmap_test
+
+import (
+	"iter"
+	"testing"
+	"time"
+
+	"github.com/ddirect/container/ttlmap"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Jitter(t *testing.T) {
+	const (
+		ttl   = time.Second
+		count = 1000
+		step  = ttl / 10
+	)
+	c := ttlmap.NewManualClock(time.Unix(1000, 0))
+	expired := make(map[time.Duration]int)
+	m := ttlmap.NewAsync(ttl, 0, func(items iter.Seq[ttlmap.Item[int, int]]) {
+		for range items {
+			expired[c.Now().Sub(time.Unix(1000, 0))]++
+		}
+	}, ttlmap.WithClock(c), ttlmap.WithJitter(0.5))
+	for i := range count {
+		m.Set(i, i)
+	}
+	for item := range m.All() {
+		assert.GreaterOrEqual(t, m.Remaining(item), ttl)
+		assert.Less(t, m.Remaining(item), ttl*3/2)
+	}
+
+	for c.Now().Before(time.Unix(1000, 0).Add(2 * ttl)) {
+		c.Advance(step)
+	}
+	assert.Zero(t, m.Len())
+	total := 0
+	for d, n := range expired {
+		assert.GreaterOrEqual(t, d, ttl)
+		assert.LessOrEqual(t, d, ttl*3/2)
+		total += n
+	}
+	assert.Equal(t, count, total)
+	// the expirations are spread over the jitter window instead of happening together
+	assert.Greater(t, len(expired), 1)
+}
+
+func Test_JitterRefresh(t *testing.T) {
+	const ttl = time.Second
+	c := ttlmap.NewManualClock(time.Unix(1000, 0))
+	m := ttlmap.NewAsync(ttl, 0, func(items iter.Seq[ttlmap.Item[int, int]]) {
+		for range items {
+			t.Fatal("the item expired")
+		}
+	}, ttlmap.WithClock(c), ttlmap.WithJitter(0.5))
+	item := m.Set(0, 0)
+	for range 20 {
+		c.Advance(ttl / 4)
+		m.Touch(item)
+		assert.GreaterOrEqual(t, m.Remaining(item), ttl)
+		assert.Less(t, m.Remaining(item), ttl*3/2)
+	}
+	m.TouchWithTTL(item, 2*ttl)
+	assert.GreaterOrEqual(t, m.Remaining(item), 2*ttl)
+	assert.Less(t, m.Remaining(item), 3*ttl)
+	m.TouchWithTTL(item, ttl/2)
+	assert.GreaterOrEqual(t, m.Remaining(item), ttl/2)
+	assert.Less(t, m.Remaining(item), ttl*3/4)
+}
+
+func Test_JitterInvalid(t *testing.T) {
+	for _, f := range []float64{0, -0.5, 1.5} {
+		assert.Panics(t, func() { ttlmap.WithJitter(f) })
+	}
+}
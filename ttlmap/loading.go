@@ -0,0 +1,223 @@
+package ttlmap
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Loader returns the value for a key which is missing from a LoadingCache.
+type Loader[K comparable, V any] func(ctx context.Context, k K) (V, error)
+
+// Refresher returns the new value for a key of a LoadingCache which is about to expire; old is the current value.
+type Refresher[K comparable, V any] func(ctx context.Context, k K, old V) (V, error)
+
+// LoadingCache is a SyncMap which loads the missing values on demand. Concurrent requests for the same missing key
+// share a single call to the loader.
+type LoadingCache[K comparable, V any] struct {
+	m        *SyncMap[K, loadResult[V]]
+	load     Loader[K, V]
+	errorTTL time.Duration
+	refresh  Refresher[K, V]
+	window   timestamp
+	mutex    sync.Mutex // protects inflight; when both are needed, it is acquired before the lock of m
+	inflight map[K]*loadCall[V]
+	closed   bool
+}
+
+type loadResult[V any] struct {
+	value V
+	err   error
+}
+
+type loadCall[V any] struct {
+	done    chan struct{}
+	refresh bool
+	loadResult[V]
+}
+
+// NewLoadingCache creates a LoadingCache using load to retrieve the missing values. ttl, accuracy and opts have the
+// same meaning as in New; by default, errors returned by load are not cached (see WithErrorCaching). The cached errors
+// are not passed to the removal listener, weigh nothing and are not subject to the expiry policy.
+func NewLoadingCache[K comparable, V any](ttl, accuracy time.Duration, load Loader[K, V], opts ...Option) *LoadingCache[K, V] {
+	opts = append(opts[:len(opts):len(opts)], adaptLoadingOptions[K, V])
+	o := makeOptions(opts)
+	c := &LoadingCache[K, V]{
+		m:        NewSync[K, loadResult[V]](ttl, accuracy, nil, opts...),
+		load:     load,
+		errorTTL: o.errorTTL,
+		window:   fromDuration(o.refreshWindow),
+		inflight: make(map[K]*loadCall[V]),
+	}
+	if o.refresh != nil {
+		refresh, ok := o.refresh.(Refresher[K, V])
+		if !ok {
+			panic(fmt.Errorf("ttlmap: %T does not match the cache types", o.refresh))
+		}
+		c.refresh = refresh
+	}
+	return c
+}
+
+// adaptLoadingOptions converts the options typed for the cache values to the results stored in the map.
+func adaptLoadingOptions[K comparable, V any](o *options) {
+	if listener, ok := o.onRemove.(RemovalListener[K, V]); ok {
+		o.onRemove = RemovalListener[K, loadResult[V]](func(k K, r loadResult[V], reason RemovalReason) {
+			if r.err == nil {
+				listener(k, r.value, reason)
+			}
+		})
+	}
+	if weigher, ok := o.weigher.(Weigher[K, V]); ok {
+		o.weigher = Weigher[K, loadResult[V]](func(k K, r loadResult[V]) int64 {
+			if r.err != nil {
+				return 0
+			}
+			return weigher(k, r.value)
+		})
+	}
+	if policy, ok := o.policy.(ExpiryPolicy[K, V]); ok {
+		o.policy = ExpiryPolicy[K, loadResult[V]](loadingPolicy[K, V]{policy})
+	}
+}
+
+// loadingPolicy applies an ExpiryPolicy to the loaded values, leaving the cached errors unchanged.
+type loadingPolicy[K comparable, V any] struct {
+	p ExpiryPolicy[K, V]
+}
+
+func (p loadingPolicy[K, V]) OnCreate(k K, r loadResult[V]) time.Duration {
+	if r.err != nil {
+		return ExpiryUnchanged
+	}
+	return p.p.OnCreate(k, r.value)
+}
+
+func (p loadingPolicy[K, V]) OnUpdate(k K, r loadResult[V], remaining time.Duration) time.Duration {
+	if r.err != nil {
+		return ExpiryUnchanged
+	}
+	return p.p.OnUpdate(k, r.value, remaining)
+}
+
+func (p loadingPolicy[K, V]) OnRead(k K, r loadResult[V], remaining time.Duration) time.Duration {
+	if r.err != nil {
+		return ExpiryUnchanged
+	}
+	return p.p.OnRead(k, r.value, remaining)
+}
+
+// GetOrLoad returns the value stored under k, calling the loader if it is missing. The loader runs in its own
+// goroutine with a context which is not canceled together with ctx: when ctx is done, GetOrLoad returns ctx.Err(),
+// but the loading continues and its result is stored for the next callers.
+func (c *LoadingCache[K, V]) GetOrLoad(ctx context.Context, k K) (V, error) {
+	if r, remaining, found := c.m.getRemaining(k); found {
+		if c.refresh != nil && r.err == nil && remaining.Before(c.window) {
+			c.refreshAhead(ctx, k, r.value)
+		}
+		return r.value, r.err
+	}
+
+	c.mutex.Lock()
+	if c.closed {
+		c.mutex.Unlock()
+		panic(ErrClosed)
+	}
+	// the value may have been stored after the previous check
+	if r, found := c.m.GetNoTouch(k); found {
+		c.mutex.Unlock()
+		return r.value, r.err
+	}
+	call, found := c.inflight[k]
+	if !found {
+		call = &loadCall[V]{done: make(chan struct{})}
+		c.inflight[k] = call
+		go c.run(context.WithoutCancel(ctx), k, call, func(ctx context.Context) (V, error) {
+			return c.load(ctx, k)
+		})
+	}
+	c.mutex.Unlock()
+
+	select {
+	case <-call.done:
+		return call.value, call.err
+	case <-ctx.Done():
+		var zero V
+		return zero, ctx.Err()
+	}
+}
+
+// refreshAhead starts refreshing k, unless a loading is already in progress.
+func (c *LoadingCache[K, V]) refreshAhead(ctx context.Context, k K, old V) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if _, found := c.inflight[k]; found || c.closed {
+		return
+	}
+	call := &loadCall[V]{done: make(chan struct{}), refresh: true}
+	c.inflight[k] = call
+	go c.run(context.WithoutCancel(ctx), k, call, func(ctx context.Context) (V, error) {
+		return c.refresh(ctx, k, old)
+	})
+}
+
+func (c *LoadingCache[K, V]) run(ctx context.Context, k K, call *loadCall[V], load func(context.Context) (V, error)) {
+	call.value, call.err = load(ctx)
+
+	c.mutex.Lock()
+	// if the key was deleted in the meantime, the result is not stored
+	if c.inflight[k] == call {
+		delete(c.inflight, k)
+		if call.err == nil {
+			// a cached error is replaced together with its ttl
+			c.m.setDefault(k, call.loadResult)
+		} else if c.errorTTL > 0 && !call.refresh { // a failed refresh keeps the old value
+			c.m.SetWithTTL(k, call.loadResult, c.errorTTL)
+		}
+	}
+	c.mutex.Unlock()
+	close(call.done)
+}
+
+// Get returns the value stored under k, without loading it.
+func (c *LoadingCache[K, V]) Get(k K) (v V, found bool) {
+	r, found := c.m.Get(k)
+	if found && r.err == nil {
+		v = r.value
+	}
+	return v, found && r.err == nil
+}
+
+// Set stores v under k; a loading in progress for k is not affected, but its result is discarded.
+func (c *LoadingCache[K, V]) Set(k K, v V) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	delete(c.inflight, k)
+	c.m.setDefault(k, loadResult[V]{value: v})
+}
+
+// Delete removes k from the cache; the result of a loading in progress for k is discarded.
+func (c *LoadingCache[K, V]) Delete(k K) bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	delete(c.inflight, k)
+	return c.m.Delete(k)
+}
+
+func (c *LoadingCache[K, V]) Len() int {
+	return c.m.Len()
+}
+
+// Close closes the cache; the results of the loadings in progress are discarded.
+func (c *LoadingCache[K, V]) Close() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.m.Close(false)
+	c.closed = true
+	clear(c.inflight)
+}
+
+func (c *LoadingCache[K, V]) Stats() Stats {
+	return c.m.Stats()
+}
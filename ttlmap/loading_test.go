@@ -0,0 +1,317 @@
+package ttlmap_test
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	"github.com/ddirect/container/ttlmap"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_LoadingCache(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		const (
+			ttl      = time.Second
+			loadTime = 100 * time.Millisecond
+			callers  = 100
+			errorKey = -1
+		)
+		var loads atomic.Int32
+		errLoad := errors.New("load failed")
+		c := ttlmap.NewLoadingCache(ttl, 0, func(ctx context.Context, k int) (string, error) {
+			loads.Add(1)
+			time.Sleep(loadTime)
+			if k == errorKey {
+				return "", errLoad
+			}
+			return time.Now().Format(time.TimeOnly), nil
+		})
+
+		getConcurrently := func(k int) (values []string, errs []error) {
+			var mutex sync.Mutex
+			var wg sync.WaitGroup
+			for range callers {
+				wg.Go(func() {
+					v, err := c.GetOrLoad(t.Context(), k)
+					mutex.Lock()
+					defer mutex.Unlock()
+					values = append(values, v)
+					errs = append(errs, err)
+				})
+			}
+			wg.Wait()
+			return
+		}
+
+		t0 := time.Now()
+		values, errs := getConcurrently(0)
+		assert.Equal(t, int32(1), loads.Load())
+		assert.Equal(t, loadTime, time.Since(t0))
+		expected := t0.Add(loadTime).Format(time.TimeOnly)
+		for i := range callers {
+			assert.NoError(t, errs[i])
+			assert.Equal(t, expected, values[i])
+		}
+		v, found := c.Get(0)
+		assert.True(t, found)
+		assert.Equal(t, expected, v)
+
+		// errors are not cached by default
+		_, errs = getConcurrently(errorKey)
+		assert.Equal(t, int32(2), loads.Load())
+		for _, err := range errs {
+			assert.ErrorIs(t, err, errLoad)
+		}
+		_, err := c.GetOrLoad(t.Context(), errorKey)
+		assert.ErrorIs(t, err, errLoad)
+		assert.Equal(t, int32(3), loads.Load())
+
+		// the value expires
+		time.Sleep(ttl)
+		assert.Zero(t, c.Len())
+		_, err = c.GetOrLoad(t.Context(), 0)
+		assert.NoError(t, err)
+		assert.Equal(t, int32(4), loads.Load())
+
+		// a canceled caller does not stop the loading
+		ctx, cancel := context.WithTimeout(t.Context(), loadTime/2)
+		defer cancel()
+		_, err = c.GetOrLoad(ctx, 1)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		time.Sleep(loadTime)
+		_, found = c.Get(1)
+		assert.True(t, found)
+		assert.Equal(t, int32(5), loads.Load())
+
+		// a deleted key discards the loading in progress
+		go c.GetOrLoad(t.Context(), 2)
+		synctest.Wait()
+		assert.False(t, c.Delete(2))
+		time.Sleep(loadTime)
+		assert.False(t, c.Delete(2))
+
+		c.Close()
+		assert.PanicsWithValue(t, ttlmap.ErrClosed, func() { c.GetOrLoad(t.Context(), 3) })
+	})
+}
+
+func Test_LoadingCacheErrorCaching(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		const ttl = time.Second
+		var loads atomic.Int32
+		errLoad := errors.New("load failed")
+		c := ttlmap.NewLoadingCache(ttl, 0, func(ctx context.Context, k int) (int, error) {
+			loads.Add(1)
+			return 0, errLoad
+		}, ttlmap.WithErrorCaching(ttl/10))
+
+		for range 3 {
+			_, err := c.GetOrLoad(t.Context(), 0)
+			assert.ErrorIs(t, err, errLoad)
+		}
+		assert.Equal(t, int32(1), loads.Load())
+		_, found := c.Get(0)
+		assert.False(t, found)
+
+		time.Sleep(ttl / 10)
+		synctest.Wait() // let the expiration complete
+		_, err := c.GetOrLoad(t.Context(), 0)
+		assert.ErrorIs(t, err, errLoad)
+		assert.Equal(t, int32(2), loads.Load())
+	})
+}
+
+func Test_LoadingCacheSetOverError(t *testing.T) {
+	const ttl = time.Hour
+	c := ttlmap.NewManualClock(time.Unix(1000, 0))
+	errLoad := errors.New("load failed")
+	cache := ttlmap.NewLoadingCache(ttl, 0, func(ctx context.Context, k int) (int, error) {
+		return 0, errLoad
+	}, ttlmap.WithClock(c), ttlmap.WithErrorCaching(time.Second))
+
+	_, err := cache.GetOrLoad(t.Context(), 0)
+	assert.ErrorIs(t, err, errLoad)
+	// the value lives for the cache ttl, not for the error one
+	cache.Set(0, 1)
+	c.Advance(2 * time.Second)
+	v, found := cache.Get(0)
+	assert.True(t, found)
+	assert.Equal(t, 1, v)
+	c.Advance(ttl)
+	assert.Zero(t, cache.Len())
+}
+
+func Test_LoadingCacheRefreshAhead(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		const (
+			ttl         = time.Second
+			window      = ttl / 5
+			refreshTime = ttl / 20
+		)
+		var loads, refreshes atomic.Int32
+		fail := false
+		c := ttlmap.NewLoadingCache(ttl, 0, func(ctx context.Context, k int) (int, error) {
+			loads.Add(1)
+			return 0, nil
+		}, ttlmap.WithExpireAfterWrite(), ttlmap.WithRefreshAhead(window, func(ctx context.Context, k int, old int) (int, error) {
+			refreshes.Add(1)
+			time.Sleep(refreshTime)
+			if fail {
+				return 0, errors.New("refresh failed")
+			}
+			return old + 1, nil
+		}))
+
+		get := func() int {
+			v, err := c.GetOrLoad(t.Context(), 0)
+			assert.NoError(t, err)
+			return v
+		}
+
+		assert.Equal(t, 0, get())
+		synctest.Wait()
+		time.Sleep(ttl - window)
+		assert.Equal(t, 0, get())
+		assert.Equal(t, int32(0), refreshes.Load())
+
+		time.Sleep(1)
+		assert.Equal(t, 0, get()) // starts the refresh
+		time.Sleep(refreshTime / 2)
+		assert.Equal(t, 0, get())
+		assert.Equal(t, int32(1), refreshes.Load())
+		time.Sleep(refreshTime / 2)
+		synctest.Wait()
+		assert.Equal(t, 1, get())
+
+		// the refreshed value got a new lifetime
+		time.Sleep(ttl - window)
+		assert.Equal(t, 1, get())
+		fail = true
+		time.Sleep(1)
+		assert.Equal(t, 1, get())
+		time.Sleep(refreshTime)
+		synctest.Wait()
+		assert.Equal(t, 1, get()) // the refresh is retried
+		time.Sleep(refreshTime)
+		assert.Equal(t, int32(3), refreshes.Load())
+		assert.Equal(t, int32(1), loads.Load())
+	})
+}
+
+func Test_LoadingCacheRemovalListener(t *testing.T) {
+	const ttl = time.Second
+	c := ttlmap.NewManualClock(time.Unix(1000, 0))
+	errLoad := errors.New("load failed")
+	type removal struct {
+		k      int
+		v      string
+		reason ttlmap.RemovalReason
+	}
+	var removals []removal
+	cache := ttlmap.NewLoadingCache(ttl, 0, func(ctx context.Context, k int) (string, error) {
+		if k < 0 {
+			return "", errLoad
+		}
+		return strconv.Itoa(k), nil
+	},
+		ttlmap.WithClock(c),
+		ttlmap.WithErrorCaching(ttl/2),
+		ttlmap.WithRemovalListener(func(k int, v string, reason ttlmap.RemovalReason) {
+			removals = append(removals, removal{k, v, reason})
+		}),
+	)
+
+	_, err := cache.GetOrLoad(t.Context(), -1)
+	assert.ErrorIs(t, err, errLoad)
+	cache.GetOrLoad(t.Context(), 1)
+	cache.GetOrLoad(t.Context(), 2)
+	cache.Set(2, "two")
+	cache.Delete(1)
+	assert.Equal(t, []removal{{2, "2", ttlmap.ReasonReplaced}, {1, "1", ttlmap.ReasonDeleted}}, removals)
+
+	// the cached error expires silently
+	c.Advance(ttl / 2)
+	assert.Equal(t, 1, cache.Len())
+	c.Advance(ttl / 2)
+	assert.Zero(t, cache.Len())
+	assert.Equal(t, removal{2, "two", ttlmap.ReasonExpired}, removals[2])
+	assert.Len(t, removals, 3)
+}
+
+func Test_LoadingCacheMaxWeight(t *testing.T) {
+	const ttl = time.Second
+	c := ttlmap.NewManualClock(time.Unix(1000, 0))
+	errLoad := errors.New("load failed")
+	cache := ttlmap.NewLoadingCache(ttl, 0, func(ctx context.Context, k int) (string, error) {
+		if k < 0 {
+			return "", errLoad
+		}
+		return strings.Repeat("x", k), nil
+	},
+		ttlmap.WithClock(c),
+		ttlmap.WithErrorCaching(ttl),
+		ttlmap.WithMaxWeight(5, func(_ int, v string) int64 { return int64(len(v)) }),
+	)
+
+	// the cached error weighs nothing
+	for _, k := range []int{-1, 2, 3} {
+		cache.GetOrLoad(t.Context(), k)
+		c.Advance(ttl / 10)
+	}
+	assert.Equal(t, 3, cache.Len())
+
+	// the items closest to expiration are evicted
+	cache.GetOrLoad(t.Context(), 4)
+	assert.Equal(t, 1, cache.Len())
+	v, found := cache.Get(4)
+	assert.True(t, found)
+	assert.Equal(t, "xxxx", v)
+}
+
+func Test_LoadingCacheExpiryPolicy(t *testing.T) {
+	const ttl = time.Second
+	c := ttlmap.NewManualClock(time.Unix(1000, 0))
+	errLoad := errors.New("load failed")
+	cache := ttlmap.NewLoadingCache(10*ttl, 0, func(ctx context.Context, k int) (string, error) {
+		if k < 0 {
+			return "", errLoad
+		}
+		return strings.Repeat("x", k), nil
+	},
+		ttlmap.WithClock(c),
+		ttlmap.WithErrorCaching(ttl),
+		ttlmap.WithExpiryPolicy[int, string](maxLenPolicy{}),
+	)
+
+	cache.GetOrLoad(t.Context(), -1)
+	cache.GetOrLoad(t.Context(), 3)
+	assert.Equal(t, 2, cache.Len())
+
+	// the cached error keeps the error ttl, the value lives for the time given by the policy
+	c.Advance(ttl)
+	assert.Equal(t, 1, cache.Len())
+	c.Advance(2 * ttl)
+	assert.Zero(t, cache.Len())
+}
+
+// maxLenPolicy keeps each value for one second per character.
+type maxLenPolicy struct{}
+
+func (maxLenPolicy) OnCreate(_ int, v string) time.Duration {
+	return time.Duration(len(v)) * time.Second
+}
+
+func (p maxLenPolicy) OnUpdate(k int, v string, _ time.Duration) time.Duration {
+	return p.OnCreate(k, v)
+}
+
+func (maxLenPolicy) OnRead(int, string, time.Duration) time.Duration {
+	return ttlmap.ExpiryUnchanged
+}
@@ -0,0 +1,63 @@
+package ttlmap_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ddirect/container/ttlmap"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Manual(t *testing.T) {
+	const ttl = time.Second
+	c := ttlmap.NewManualClock(time.Unix(1000, 0))
+	m := ttlmap.NewManual[int, int](ttl, 0, ttlmap.WithClock(c), ttlmap.WithMaxLen(2))
+
+	_, ok := m.NextDeadline()
+	assert.False(t, ok)
+
+	m.Set(0, 0)
+	c.Advance(ttl / 4)
+	m.Set(1, 1)
+	assert.Zero(t, c.Pending())
+
+	deadline, ok := m.NextDeadline()
+	assert.True(t, ok)
+	assert.Equal(t, time.Unix(1000, 0).Add(ttl), deadline)
+
+	expire := func(now time.Time) (keys []int) {
+		for item := range m.Expire(now) {
+			keys = append(keys, item.Key())
+		}
+		return
+	}
+	assert.Empty(t, expire(deadline.Add(-time.Millisecond)))
+	assert.Equal(t, []int{0}, expire(deadline))
+	assert.Equal(t, 1, m.Len())
+
+	deadline, _ = m.NextDeadline()
+	assert.Equal(t, time.Unix(1000, 0).Add(ttl*5/4), deadline)
+
+	// evictions are due immediately
+	m.Set(2, 2)
+	m.Set(3, 3)
+	deadline, _ = m.NextDeadline()
+	assert.Equal(t, c.Now(), deadline)
+	assert.Equal(t, []int{1}, expire(c.Now()))
+
+	m.Close(true)
+	deadline, ok = m.NextDeadline()
+	assert.True(t, ok)
+	assert.Equal(t, c.Now(), deadline)
+	assert.ElementsMatch(t, []int{2, 3}, expire(c.Now()))
+	assert.Zero(t, m.Len())
+	_, ok = m.NextDeadline()
+	assert.False(t, ok)
+	assert.Zero(t, c.Pending())
+}
+
+func Test_ManualExpirePanics(t *testing.T) {
+	m := ttlmap.NewAsync[int, int](time.Second, 0, nil)
+	defer m.Close(false)
+	assert.Panics(t, func() { m.Expire(time.Now()) })
+}
@@ -0,0 +1,80 @@
+package ttlmap_test
+
+import (
+	"bytes"
+	"iter"
+	"testing"
+	"time"
+
+	"github.com/ddirect/container/ttlmap"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_MaxAge(t *testing.T) {
+	const ttl = time.Second
+	start := time.Unix(1000, 0)
+	c := ttlmap.NewManualClock(start)
+	expired := make(map[int]time.Duration)
+	m := ttlmap.NewAsync(ttl, ttl/5, func(items iter.Seq[ttlmap.Item[int, int]]) {
+		for item := range items {
+			expired[item.Key()] = c.Now().Sub(start)
+		}
+	}, ttlmap.WithClock(c), ttlmap.WithMaxAge(2*ttl))
+
+	m.Set(0, 0)
+	m.Set(1, 1)
+	m.SetWithTTL(2, 2, 5*ttl)
+	for range 5 {
+		c.Advance(ttl / 2)
+		m.Get(0)
+		m.Get(2)
+	}
+	// 1 is not accessed, 0 and 2 are accessed continuously, but expire at the maximum age
+	assert.Equal(t, ttl+ttl/5, expired[1])
+	assert.Equal(t, 2*ttl+ttl/10, expired[0])
+	assert.Equal(t, 2*ttl+ttl/10, expired[2])
+
+	// writing the value resets the age
+	m.Set(3, 3)
+	c.Advance(ttl / 2)
+	m.Get(3)
+	c.Advance(ttl)
+	m.Set(3, 3)
+	for range 4 {
+		c.Advance(ttl / 2)
+		m.Get(3)
+	}
+	assert.NotContains(t, expired, 3)
+	c.Advance(ttl / 2)
+	assert.Equal(t, 6*ttl+ttl/10, expired[3])
+	m.Close(false)
+}
+
+func Test_MaxAgeSnapshot(t *testing.T) {
+	const ttl = time.Second
+	c := ttlmap.NewManualClock(time.Unix(1000, 0))
+	m := ttlmap.NewManual[int, string](ttl, 0, ttlmap.WithClock(c), ttlmap.WithMaxAge(2*ttl))
+	m.Set(0, "zero")
+	c.Advance(ttl * 3 / 2)
+	m.Get(0)
+	assert.Equal(t, ttl/2, m.Remaining(m.GetNoTouch(0)))
+
+	var b bytes.Buffer
+	require.NoError(t, ttlmap.NewEncoder(&b, intCodec{}, stringCodec{}).Encode(m))
+	data := b.Bytes()
+
+	// the age is restored, so the item cannot be extended beyond the maximum age
+	m2 := ttlmap.NewManual[int, string](ttl, 0, ttlmap.WithClock(c), ttlmap.WithMaxAge(2*ttl))
+	require.NoError(t, ttlmap.NewDecoder(bytes.NewReader(data), intCodec{}, stringCodec{}).Decode(m2))
+	item := m2.Get(0)
+	assert.Equal(t, ttl/2, m2.Remaining(item))
+
+	// version 1 snapshots have no age: restored items are considered new
+	v1 := []byte{'T', 'T', 'L', 'M', 1, 1, 0, 0, 0, 0, 0, 1, 2, 0, 0, 0, 1, 'x'}
+	m3 := ttlmap.NewManual[int, string](ttl, 0, ttlmap.WithClock(c), ttlmap.WithMaxAge(2*ttl))
+	require.NoError(t, ttlmap.NewDecoder(bytes.NewReader(v1), intCodec{}, stringCodec{}).Decode(m3))
+	item = m3.Get(1)
+	assert.Equal(t, "x", *item.Value())
+	assert.Equal(t, ttl, m3.Remaining(item))
+}
@@ -0,0 +1,133 @@
+package ttlmap_test
+
+import (
+	"iter"
+	"testing"
+	"time"
+
+	"github.com/ddirect/container/ttlmap"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_MaxLen(t *testing.T) {
+	const ttl = time.Second
+	c := ttlmap.NewManualClock(time.Unix(1000, 0))
+	var evicted, expired []int
+	m := ttlmap.NewAsync(ttl, 0, func(items iter.Seq[ttlmap.Item[int, int]]) {
+		for item := range items {
+			assert.Equal(t, item.Key(), *item.Value())
+			if item.Evicted() {
+				assert.False(t, item.Present())
+				evicted = append(evicted, item.Key())
+			} else {
+				assert.True(t, item.Present())
+				expired = append(expired, item.Key())
+			}
+		}
+	}, ttlmap.WithClock(c), ttlmap.WithMaxLen(3))
+
+	for i := range 3 {
+		m.Set(i, i)
+		c.Advance(ttl / 10)
+	}
+	m.Touch(m.GetNoTouch(0))
+	c.Advance(ttl / 10)
+	m.Set(2, 2)
+	assert.Equal(t, 3, m.Len())
+
+	m.Set(3, 3)
+	assert.Equal(t, 3, m.Len())
+	assert.False(t, m.Exists(1))
+	assert.Empty(t, evicted)
+	c.Advance(0)
+	assert.Equal(t, []int{1}, evicted)
+
+	m.SetWithTTL(4, 4, ttl/2)
+	m.Set(5, 5)
+	c.Advance(0)
+	assert.Equal(t, []int{1, 0, 4}, evicted)
+	assert.Empty(t, expired)
+
+	c.Advance(ttl)
+	assert.Equal(t, []int{1, 0, 4}, evicted)
+	assert.ElementsMatch(t, []int{2, 3, 5}, expired)
+	assert.Zero(t, m.Len())
+	assert.Zero(t, c.Pending())
+}
+
+func Test_MaxLenRefillFromHandler(t *testing.T) {
+	const ttl = time.Second
+	weigher := func(int, int) int64 { return 1 }
+	for name, test := range map[string]struct {
+		opts    []ttlmap.Option
+		evicted []int
+	}{
+		// the limit is exceeded until the item being delivered is removed
+		"heap":  {opts: []ttlmap.Option{ttlmap.WithMaxLen(1)}},
+		"wheel": {opts: []ttlmap.Option{ttlmap.WithMaxLen(1), ttlmap.WithTimingWheel()}},
+		// the new item is the only one which can be evicted
+		"weight":      {opts: []ttlmap.Option{ttlmap.WithMaxWeight(1, weigher)}, evicted: []int{1}},
+		"wheelWeight": {opts: []ttlmap.Option{ttlmap.WithMaxWeight(1, weigher), ttlmap.WithTimingWheel()}, evicted: []int{1}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			c := ttlmap.NewManualClock(time.Unix(1000, 0))
+			var expired, evicted []int
+			var m *ttlmap.Map[int, int]
+			m = ttlmap.NewAsync(ttl, ttl/10, func(items iter.Seq[ttlmap.Item[int, int]]) {
+				for item := range items {
+					if item.Evicted() {
+						evicted = append(evicted, item.Key())
+						continue
+					}
+					expired = append(expired, item.Key())
+					if item.Key() < 3 {
+						m.Set(item.Key()+1, item.Key()+1)
+						assert.True(t, item.Present())
+					}
+				}
+			}, append(test.opts, ttlmap.WithClock(c))...)
+			m.Set(0, 0)
+			for range 4 {
+				c.Advance(ttl * 2)
+			}
+			assert.Equal(t, test.evicted, evicted)
+			if test.evicted == nil {
+				assert.Equal(t, []int{0, 1, 2, 3}, expired)
+			} else {
+				assert.Equal(t, []int{0}, expired)
+			}
+			assert.Zero(t, m.Len())
+		})
+	}
+}
+
+func Test_MaxLenEvictFromHandler(t *testing.T) {
+	const ttl = time.Second
+	for _, wheel := range []bool{false, true} {
+		c := ttlmap.NewManualClock(time.Unix(1000, 0))
+		opts := []ttlmap.Option{ttlmap.WithClock(c), ttlmap.WithMaxLen(2)}
+		if wheel {
+			opts = append(opts, ttlmap.WithTimingWheel())
+		}
+		var delivered []int
+		var m *ttlmap.Map[int, int]
+		m = ttlmap.NewAsync(ttl, ttl/10, func(items iter.Seq[ttlmap.Item[int, int]]) {
+			for item := range items {
+				delivered = append(delivered, item.Key())
+				if item.Key() == 0 {
+					// the next item closest to expiration is evicted instead
+					m.Set(5, 5)
+					m.Touch(item)
+				}
+			}
+		}, opts...)
+		m.Set(0, 0)
+		c.Advance(ttl / 2)
+		m.Set(1, 1)
+		c.Advance(ttl * 3 / 4)
+		assert.Equal(t, []int{0, 1}, delivered, "wheel: %v", wheel)
+		assert.True(t, m.Exists(0))
+		assert.True(t, m.Exists(5))
+		assert.False(t, m.Exists(1))
+	}
+}
@@ -0,0 +1,185 @@
+package ttlmap
+
+import (
+	"fmt"
+	"time"
+)
+
+// Option configures a Map at construction time.
+type Option func(*options)
+
+type options struct {
+	clock         Clock
+	maxLen        int
+	afterWrite    bool
+	errorTTL      time.Duration
+	refreshWindow time.Duration
+	refresh       any // Refresher[K, V]
+	stats         bool
+	onRemove      any // RemovalListener[K, V]
+	maxWeight     int64
+	weigher       any // Weigher[K, V]
+	onClose       func()
+	maxAge        time.Duration
+	wheel         bool
+	policy        any // ExpiryPolicy[K, V]
+	jitter        float64
+}
+
+func makeOptions(opts []Option) options {
+	o := options{
+		clock: systemClock{},
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// WithClock makes the map use c instead of the system clock.
+func WithClock(c Clock) Option {
+	return func(o *options) {
+		o.clock = c
+	}
+}
+
+// WithScheduler makes the map use s for its expiration timer, together with the other maps using it. It replaces
+// the clock set with WithClock.
+func WithScheduler(s *Scheduler) Option {
+	return WithClock(s)
+}
+
+// WithMaxLen limits the number of items in the map to n. When inserting a new item in a full map, the item closest
+// to expiration is evicted and delivered to the expired items handler, where it can be told apart with Item.Evicted.
+// The expired item being delivered to the handler is never evicted, so inserting from the handler can exceed the limit
+// until it is removed.
+func WithMaxLen(n int) Option {
+	if n < 1 {
+		panic(fmt.Errorf("ttlmap: invalid maximum length: %d", n))
+	}
+	return func(o *options) {
+		o.maxLen = n
+	}
+}
+
+// WithExpireAfterWrite makes the items expire a fixed time after they were last written with Set, SetWithTTL or one
+// of the WithTTL methods: reading or touching them does not extend their lifetime. By default items expire a fixed
+// time after they were last accessed.
+func WithExpireAfterWrite() Option {
+	return func(o *options) {
+		o.afterWrite = true
+	}
+}
+
+// WithMaxAge limits the lifetime of the items to maxAge after they were last written with Set or SetWithTTL, or
+// created, even if they are accessed continuously. Items reaching the maximum age are delivered to the expired items
+// handler no later than accuracy/2 after it.
+func WithMaxAge(maxAge time.Duration) Option {
+	if maxAge < time.Millisecond {
+		panic(fmt.Errorf("ttlmap: invalid maximum age: %v", maxAge))
+	}
+	return func(o *options) {
+		o.maxAge = maxAge
+	}
+}
+
+// WithJitter extends the lifetime of the items by a random amount, up to fraction times their time-to-live, every
+// time it is set or refreshed, so that items inserted together do not expire together. The accuracy applies on top of
+// the jitter; the maximum age, if set, is not extended.
+func WithJitter(fraction float64) Option {
+	if !(fraction > 0 && fraction <= 1) {
+		panic(fmt.Errorf("ttlmap: invalid jitter: %v", fraction))
+	}
+	return func(o *options) {
+		o.jitter = fraction
+	}
+}
+
+// WithErrorCaching makes a LoadingCache store the errors returned by the loader for ttl, so that the loading is not
+// retried until then. It has no effect on the other map types.
+func WithErrorCaching(ttl time.Duration) Option {
+	itemTTL(ttl)
+	return func(o *options) {
+		o.errorTTL = ttl
+	}
+}
+
+// WithRefreshAhead makes a LoadingCache call refresh in the background for the items which are read less than window
+// before their expiration, so that frequently used items are replaced before they expire. Until refresh returns, the
+// old value is still returned; if refresh fails, the old value is kept. It has no effect on the other map types.
+// Since by default reading an item extends its lifetime, this option is mostly useful together with
+// WithExpireAfterWrite.
+func WithRefreshAhead[K comparable, V any](window time.Duration, refresh Refresher[K, V]) Option {
+	if window <= 0 {
+		panic(fmt.Errorf("ttlmap: invalid refresh window: %v", window))
+	}
+	return func(o *options) {
+		o.refreshWindow = window
+		o.refresh = refresh
+	}
+}
+
+// WithStats enables the collection of the statistics returned by Stats. The counters are updated without
+// synchronization, except the one counting the timer firings, so their cost is negligible.
+func WithStats() Option {
+	return func(o *options) {
+		o.stats = true
+	}
+}
+
+// WithRemovalListener makes the map call listener for every item which is removed, including the expired ones, and
+// for every value which is replaced, so that the resources associated to the values can be released in one place.
+// The listener is called synchronously by the goroutine causing the removal, after the map has been updated; for
+// the expired items, this is the goroutine iterating the expired items. It must not call any method of the map.
+func WithRemovalListener[K comparable, V any](listener RemovalListener[K, V]) Option {
+	return func(o *options) {
+		o.onRemove = listener
+	}
+}
+
+// WithMaxWeight limits the total weight of the items in the map to max, the weight of each item being computed by
+// weigher when its value is stored. When the limit is exceeded, the items closest to expiration are evicted and
+// delivered to the expired items handler, as with WithMaxLen, until the map is back under the limit.
+func WithMaxWeight[K comparable, V any](max int64, weigher Weigher[K, V]) Option {
+	if max < 1 {
+		panic(fmt.Errorf("ttlmap: invalid maximum weight: %d", max))
+	}
+	return func(o *options) {
+		o.maxWeight = max
+		o.weigher = weigher
+	}
+}
+
+// WithCloseListener makes the map call f when it is closed, after the last delivery of the expired items when
+// flushing. Several listeners can be added; they are called in order.
+func WithCloseListener(f func()) Option {
+	return func(o *options) {
+		if prev := o.onClose; prev != nil {
+			o.onClose = func() {
+				prev()
+				f()
+			}
+		} else {
+			o.onClose = f
+		}
+	}
+}
+
+// WithTimingWheel makes the map keep the items in a hierarchical timing wheel with slots accuracy/2 wide, instead of
+// a heap: inserting, refreshing and removing an item becomes O(1) instead of O(log n), which pays off with millions
+// of items. Items expiring in the same slot are removed in no particular order, and the evictions caused by
+// WithMaxLen and WithMaxWeight pick any item of the first slot. The accuracy must not be 0, also when changed with
+// SetAccuracy, which resizes the slots.
+func WithTimingWheel() Option {
+	return func(o *options) {
+		o.wheel = true
+	}
+}
+
+// WithExpiryPolicy makes the map compute the lifetime of the items with policy when they are created, written or
+// read, instead of using the map time-to-live. The methods taking an explicit ttl bypass the policy.
+func WithExpiryPolicy[K comparable, V any](policy ExpiryPolicy[K, V]) Option {
+	return func(o *options) {
+		o.policy = policy
+	}
+}
@@ -0,0 +1,43 @@
+package ttlmap
+
+import "github.com/ddirect/container/internal/rankedmap"
+
+// Pause stops the expiration: until Resume is called, the time is frozen for the map, so the remaining lifetime of
+// the items does not decrease and none of them expires. Evicted items are delivered after Resume. Close resumes the
+// map implicitly.
+func (m *Map[K, V]) Pause() {
+	m.checkOpen()
+	if m.paused {
+		return
+	}
+	m.pausedAt = m.now()
+	m.paused = true
+	// if the timer cannot be stopped, the cleanup is already pending and it marks the timer as stopped
+	if m.timer != nil && m.timer.Stop() {
+		m.timer = nil
+	}
+}
+
+// Resume restarts the expiration after Pause, shifting the expiration of all the items by the paused time.
+func (m *Map[K, V]) Resume() {
+	m.checkOpen()
+	if !m.paused {
+		return
+	}
+	m.paused = false
+	now := m.now()
+	if delta := now - m.pausedAt; delta > 0 {
+		m.m.Rerank(func(item rankedmap.MapItem[K, timestamp, entry[V]]) timestamp {
+			item.Value().created += delta
+			return item.Rank() + delta
+		})
+	}
+	if m.timer == nil {
+		m.restartTimer(now)
+	}
+}
+
+// Paused reports whether the map is paused.
+func (m *Map[K, V]) Paused() bool {
+	return m.paused
+}
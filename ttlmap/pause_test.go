@@ -0,0 +1,71 @@
+package ttlmap_test
+
+import (
+	"iter"
+	"testing"
+	"time"
+
+	"github.com/ddirect/container/ttlmap"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Pause(t *testing.T) {
+	const ttl = time.Second
+	c := ttlmap.NewManualClock(time.Unix(1000, 0))
+	var expired []int
+	m := ttlmap.NewAsync(ttl, 0, func(items iter.Seq[ttlmap.Item[int, int]]) {
+		for item := range items {
+			expired = append(expired, item.Key())
+		}
+	}, ttlmap.WithClock(c), ttlmap.WithMaxLen(3))
+
+	m.Set(0, 0)
+	c.Advance(ttl / 4)
+	m.Set(1, 1)
+	c.Advance(ttl / 4)
+
+	m.Pause()
+	assert.True(t, m.Paused())
+	assert.Zero(t, c.Pending())
+	c.Advance(ttl * 10)
+	assert.Empty(t, expired)
+	assert.Equal(t, ttl/2, m.Remaining(m.GetNoTouch(0)))
+	assert.Equal(t, c.Now().Add(ttl/2), m.ExpiresAt(m.GetNoTouch(0)))
+
+	// items written while paused start their lifetime at the resume; evictions are delivered after it
+	m.Set(2, 2)
+	m.Set(3, 3)
+	assert.False(t, m.Exists(0))
+	assert.Empty(t, expired)
+	c.Advance(ttl)
+
+	m.Resume()
+	assert.False(t, m.Paused())
+	assert.Equal(t, ttl*3/4, m.Remaining(m.GetNoTouch(1)))
+	assert.Equal(t, c.Now().Add(ttl*3/4), m.ExpiresAt(m.GetNoTouch(1)))
+	c.Advance(0)
+	assert.Equal(t, []int{0}, expired)
+	c.Advance(ttl * 3 / 4)
+	assert.Equal(t, []int{0, 1}, expired)
+	c.Advance(ttl / 4)
+	assert.Equal(t, []int{0, 1, 2, 3}, expired)
+	assert.Zero(t, c.Pending())
+}
+
+func Test_PauseClose(t *testing.T) {
+	const ttl = time.Second
+	c := ttlmap.NewManualClock(time.Unix(1000, 0))
+	var expired []int
+	m := ttlmap.NewAsync(ttl, 0, func(items iter.Seq[ttlmap.Item[int, int]]) {
+		for item := range items {
+			expired = append(expired, item.Key())
+		}
+	}, ttlmap.WithClock(c))
+
+	m.Set(0, 0)
+	m.Pause()
+	m.Close(true)
+	c.Advance(0)
+	assert.Equal(t, []int{0}, expired)
+	assert.Zero(t, c.Pending())
+}
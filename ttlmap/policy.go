@@ -0,0 +1,33 @@
+package ttlmap
+
+import "time"
+
+// ExpiryUnchanged is returned by the ExpiryPolicy methods to leave the expiration of the item as it is; any negative
+// lifetime has the same effect.
+const ExpiryUnchanged time.Duration = -1
+
+// ExpiryPolicy computes the lifetime of the items from their key and value, for maps created with WithExpiryPolicy.
+// Each method returns the new lifetime of the item, counted from now, or ExpiryUnchanged. Like the map time-to-live,
+// the lifetime can be extended by up to the accuracy; it must not call any method of the map.
+type ExpiryPolicy[K comparable, V any] interface {
+	// OnCreate is called when an item is inserted; the value is the zero value for GetOrCreate, whose value is
+	// usually set afterwards and reported with Updated. ExpiryUnchanged keeps the map time-to-live.
+	OnCreate(k K, v V) time.Duration
+	// OnUpdate is called when the value of an item is replaced; remaining is the current lifetime of the item.
+	OnUpdate(k K, v V, remaining time.Duration) time.Duration
+	// OnRead is called when an item is read or touched; remaining is the current lifetime of the item.
+	OnRead(k K, v V, remaining time.Duration) time.Duration
+}
+
+// applyPolicy sets the lifetime returned by the policy.
+func (m *Map[K, V]) applyPolicy(item Item[K, V], lifetime time.Duration, now timestamp) {
+	if lifetime < 0 {
+		return
+	}
+	// 0 would mean the map time-to-live
+	m.setTTL(item, max(fromDuration(lifetime), 1), now)
+}
+
+func (m *Map[K, V]) remaining(item Item[K, V], now timestamp) time.Duration {
+	return toDuration(max(item.Rank()-now, 0))
+}
@@ -0,0 +1,125 @@
+package ttlmap_test
+
+import (
+	"fmt"
+	"iter"
+	"testing"
+	"time"
+
+	"github.com/ddirect/container/ttlmap"
+	"github.com/stretchr/testify/assert"
+)
+
+type response struct {
+	body   string
+	maxAge time.Duration // 0 to use the map ttl
+}
+
+// maxAgePolicy takes the lifetime from the value, like Cache-Control, and never extends it on read.
+type maxAgePolicy struct {
+	reads int
+}
+
+func (maxAgePolicy) OnCreate(_ string, v response) time.Duration {
+	if v.maxAge == 0 {
+		return ttlmap.ExpiryUnchanged
+	}
+	return v.maxAge
+}
+
+func (p maxAgePolicy) OnUpdate(k string, v response, _ time.Duration) time.Duration {
+	return p.OnCreate(k, v)
+}
+
+func (p *maxAgePolicy) OnRead(string, response, time.Duration) time.Duration {
+	p.reads++
+	return ttlmap.ExpiryUnchanged
+}
+
+func Test_ExpiryPolicy(t *testing.T) {
+	const ttl = time.Second
+	p := &maxAgePolicy{}
+	c := ttlmap.NewManualClock(time.Unix(1000, 0))
+	var expired []string
+	m := ttlmap.NewAsync(ttl, 0, func(items iter.Seq[ttlmap.Item[string, response]]) {
+		for item := range items {
+			expired = append(expired, item.Key())
+		}
+	}, ttlmap.WithClock(c), ttlmap.WithExpiryPolicy[string, response](p))
+	m.Set("a", response{"a", 0})
+	m.Set("b", response{"b", 3 * ttl})
+	m.Set("c", response{"c", ttl / 2})
+	assert.Equal(t, 3*ttl, m.Remaining(m.GetNoTouch("b")))
+
+	// reads do not extend the lifetime
+	m.Get("a")
+	m.Get("c")
+	assert.Equal(t, 2, p.reads)
+	c.Advance(ttl / 2)
+	assert.Equal(t, []string{"c"}, expired)
+
+	// updates take the lifetime from the new value, even if shorter
+	m.Set("b", response{"b", ttl / 2})
+	assert.Equal(t, ttl/2, m.Remaining(m.GetNoTouch("b")))
+	c.Advance(ttl / 2)
+	assert.ElementsMatch(t, []string{"c", "a", "b"}, expired)
+
+	// the explicit ttl bypasses the policy
+	m.SetWithTTL("d", response{"d", ttl}, 5*ttl)
+	assert.Equal(t, 5*ttl, m.Remaining(m.GetNoTouch("d")))
+
+	// values set through GetOrCreate are reported with Updated
+	item, _ := m.GetOrCreate("e")
+	assert.Equal(t, ttl, m.Remaining(item))
+	*item.Value() = response{"e", 2 * ttl}
+	m.Updated(item)
+	assert.Equal(t, 2*ttl, m.Remaining(item))
+}
+
+func Test_ExpiryPolicyTypes(t *testing.T) {
+	assert.PanicsWithError(t, "ttlmap: *ttlmap_test.maxAgePolicy does not match the map types", func() {
+		ttlmap.NewManual[string, int](time.Second, 0, ttlmap.WithExpiryPolicy[string, response](&maxAgePolicy{}))
+	})
+}
+
+// recordingPolicy records the calls, keeping the lifetime unchanged.
+type recordingPolicy struct {
+	calls []string
+}
+
+func (p *recordingPolicy) OnCreate(k string, v int) time.Duration {
+	p.calls = append(p.calls, fmt.Sprintf("create %s=%d", k, v))
+	return ttlmap.ExpiryUnchanged
+}
+
+func (p *recordingPolicy) OnUpdate(k string, v int, _ time.Duration) time.Duration {
+	p.calls = append(p.calls, fmt.Sprintf("update %s=%d", k, v))
+	return ttlmap.ExpiryUnchanged
+}
+
+func (p *recordingPolicy) OnRead(k string, v int, _ time.Duration) time.Duration {
+	p.calls = append(p.calls, fmt.Sprintf("read %s=%d", k, v))
+	return ttlmap.ExpiryUnchanged
+}
+
+func Test_ExpiryPolicySyncGetOrCreate(t *testing.T) {
+	p := &recordingPolicy{}
+	var weighed []int
+	s := ttlmap.NewSync[string, int](time.Second, 0, nil,
+		ttlmap.WithExpiryPolicy[string, int](p),
+		ttlmap.WithMaxWeight(100, func(_ string, v int) int64 {
+			weighed = append(weighed, v)
+			return 1
+		}))
+	defer s.Close(false)
+
+	// the created value is seen once, as a creation
+	v, found := s.GetOrCreate("a", func() int { return 1 })
+	assert.False(t, found)
+	assert.Equal(t, 1, v)
+	v, found = s.GetOrCreate("a", func() int { return 2 })
+	assert.True(t, found)
+	assert.Equal(t, 1, v)
+	assert.Equal(t, []string{"create a=1", "read a=1"}, p.calls)
+	assert.Equal(t, []int{1}, weighed)
+}
@@ -0,0 +1,120 @@
+package ttlmap_test
+
+import (
+	"iter"
+	"testing"
+	"time"
+
+	"github.com/ddirect/container/ttlmap"
+	"github.com/stretchr/testify/assert"
+)
+
+func newReconfigMap(c *ttlmap.ManualClock, ttl, accuracy time.Duration) (*ttlmap.Map[int, int], *[]int) {
+	var expired []int
+	m := ttlmap.NewAsync(ttl, accuracy, func(items iter.Seq[ttlmap.Item[int, int]]) {
+		for item := range items {
+			expired = append(expired, item.Key())
+		}
+	}, ttlmap.WithClock(c))
+	return m, &expired
+}
+
+func Test_SetTTL(t *testing.T) {
+	const ttl = time.Second
+	for _, rescale := range []bool{false, true} {
+		c := ttlmap.NewManualClock(time.Unix(1000, 0))
+		m, expired := newReconfigMap(c, ttl, 0)
+		m.Set(0, 0)
+		m.SetWithTTL(1, 1, ttl*2)
+
+		m.SetTTL(ttl*3, rescale)
+		m.Set(2, 2)
+		c.Advance(ttl)
+		if rescale {
+			assert.Empty(t, *expired)
+		} else {
+			assert.Equal(t, []int{0}, *expired)
+		}
+		c.Advance(ttl)
+		if rescale {
+			assert.Equal(t, []int{1}, *expired)
+		} else {
+			assert.Equal(t, []int{0, 1}, *expired)
+		}
+		c.Advance(ttl)
+		assert.Equal(t, 0, m.Len())
+
+		m.Set(0, 0)
+		m.Set(1, 1)
+		m.SetTTL(ttl/2, rescale)
+		c.Advance(ttl / 2)
+		if rescale {
+			assert.Equal(t, 0, m.Len())
+		} else {
+			assert.Equal(t, 2, m.Len())
+		}
+		m.Close(false)
+	}
+}
+
+func Test_SetAccuracy(t *testing.T) {
+	const ttl = time.Second
+	for _, rescale := range []bool{false, true} {
+		c := ttlmap.NewManualClock(time.Unix(1000, 0))
+		m, expired := newReconfigMap(c, ttl, ttl/2)
+		m.Set(0, 0)
+		item := m.GetNoTouch(0)
+		assert.Equal(t, ttl*5/4, m.Remaining(item))
+
+		m.SetAccuracy(0, rescale)
+		if rescale {
+			assert.Equal(t, ttl, m.Remaining(item))
+		} else {
+			assert.Equal(t, ttl*5/4, m.Remaining(item))
+		}
+		// the timer no longer waits for accuracy/2 after the expiration
+		c.Advance(m.Remaining(item))
+		assert.Equal(t, []int{0}, *expired)
+		m.Close(false)
+	}
+}
+
+func Test_SetTTLInvalid(t *testing.T) {
+	m := ttlmap.NewManual[int, int](time.Second, time.Second/2)
+	assert.Panics(t, func() { m.SetTTL(0, false) })
+	assert.Panics(t, func() { m.SetTTL(time.Second/2, false) })
+	assert.Panics(t, func() { m.SetAccuracy(time.Second, false) })
+	assert.Panics(t, func() { m.SetAccuracy(-1, false) })
+	m.SetTTL(time.Second/2+time.Millisecond, false)
+}
+
+func Test_SetAccuracyWheel(t *testing.T) {
+	const (
+		ttl      = time.Second
+		accuracy = ttl / 10
+	)
+	start := time.Unix(1000, 0)
+	c := ttlmap.NewManualClock(start)
+	lifetimes := make(map[int]time.Duration)
+	m := ttlmap.NewAsync(ttl, ttl/2, func(items iter.Seq[ttlmap.Item[int, int]]) {
+		for item := range items {
+			lifetimes[item.Key()] = c.Now().Sub(start.Add(time.Duration(item.Key()) * ttl / 20))
+		}
+	}, ttlmap.WithClock(c), ttlmap.WithTimingWheel())
+	assert.Panics(t, func() { m.SetAccuracy(0, false) })
+
+	for i := range 10 {
+		m.Set(i, i)
+		c.Advance(ttl / 20)
+	}
+	m.SetAccuracy(accuracy, true)
+	for range 200 {
+		c.Advance(ttl / 100)
+	}
+	// the lifetime is bound by the new accuracy
+	assert.Len(t, lifetimes, 10)
+	for k, d := range lifetimes {
+		assert.GreaterOrEqual(t, d, ttl, "key %d", k)
+		assert.LessOrEqual(t, d, ttl+accuracy, "key %d", k)
+	}
+}
@@ -0,0 +1,54 @@
+package ttlmap
+
+import "fmt"
+
+// RemovalReason tells why an item was removed from the map.
+type RemovalReason int
+
+const (
+	ReasonExpired  RemovalReason = iota // the item expired
+	ReasonDeleted                       // the item was deleted with Delete or DeleteKey
+	ReasonReplaced                      // the value was replaced by Set or SetWithTTL; the key is still in the map
+	ReasonCleared                       // the map was cleared
+	ReasonEvicted                       // the item was evicted by WithMaxLen or WithMaxWeight, on insertion or growth
+)
+
+func (r RemovalReason) String() string {
+	switch r {
+	case ReasonExpired:
+		return "expired"
+	case ReasonDeleted:
+		return "deleted"
+	case ReasonReplaced:
+		return "replaced"
+	case ReasonCleared:
+		return "cleared"
+	case ReasonEvicted:
+		return "evicted"
+	default:
+		return fmt.Sprintf("RemovalReason(%d)", int(r))
+	}
+}
+
+// RemovalListener is called with the key and the value of every item removed from a map, or whose value is replaced.
+type RemovalListener[K comparable, V any] func(k K, v V, reason RemovalReason)
+
+// removed updates the total weight after the item has been removed from the map and notifies the listener.
+func (m *Map[K, V]) removed(item Item[K, V], reason RemovalReason) {
+	m.weight -= item.entry().weight
+	if m.onRemove != nil {
+		m.onRemove(item.Key(), *item.Value(), reason)
+	}
+}
+
+// store sets the value of the item, notifying the replacement of the previous value if found.
+func (m *Map[K, V]) store(item Item[K, V], found bool, v V) {
+	if found && m.onRemove != nil {
+		old := *item.Value()
+		*item.Value() = v
+		m.onRemove(item.Key(), old, ReasonReplaced)
+	} else {
+		*item.Value() = v
+	}
+	m.weigh(item)
+}
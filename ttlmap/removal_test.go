@@ -0,0 +1,78 @@
+package ttlmap_test
+
+import (
+	"iter"
+	"testing"
+	"time"
+
+	"github.com/ddirect/container/ttlmap"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_RemovalListener(t *testing.T) {
+	const ttl = time.Second
+	type removal struct {
+		key    int
+		value  string
+		reason ttlmap.RemovalReason
+	}
+	c := ttlmap.NewManualClock(time.Unix(1000, 0))
+	var removals []removal
+	m := ttlmap.NewAsync(ttl, 0, func(items iter.Seq[ttlmap.Item[int, string]]) {
+		for range items {
+		}
+	}, ttlmap.WithClock(c), ttlmap.WithMaxLen(3), ttlmap.WithRemovalListener(func(k int, v string, reason ttlmap.RemovalReason) {
+		removals = append(removals, removal{k, v, reason})
+	}))
+	assertRemovals := func(expected ...removal) {
+		assert.ElementsMatch(t, expected, removals)
+		removals = nil
+	}
+
+	m.Set(0, "a")
+	m.Set(0, "b")
+	m.SetWithTTL(0, "c", ttl)
+	assertRemovals(removal{0, "a", ttlmap.ReasonReplaced}, removal{0, "b", ttlmap.ReasonReplaced})
+
+	m.Set(1, "d")
+	m.Set(2, "e")
+	m.Set(3, "f")
+	assertRemovals(removal{0, "c", ttlmap.ReasonEvicted})
+
+	m.DeleteKey(1)
+	m.Delete(m.GetNoTouch(2))
+	m.DeleteKey(1)
+	assertRemovals(removal{1, "d", ttlmap.ReasonDeleted}, removal{2, "e", ttlmap.ReasonDeleted})
+
+	c.Advance(ttl / 2)
+	m.Set(4, "g")
+	m.Set(5, "h")
+	c.Advance(ttl / 2)
+	assertRemovals(removal{3, "f", ttlmap.ReasonExpired})
+
+	m.Clear()
+	assertRemovals(removal{4, "g", ttlmap.ReasonCleared}, removal{5, "h", ttlmap.ReasonCleared})
+
+	m.Set(6, "i")
+	m.Close(true)
+	c.Advance(0)
+	assertRemovals(removal{6, "i", ttlmap.ReasonExpired})
+
+	assert.Equal(t, "evicted", ttlmap.ReasonEvicted.String())
+	assert.Panics(t, func() {
+		ttlmap.New[int, int](ttl, 0, ttlmap.WithRemovalListener(func(int, string, ttlmap.RemovalReason) {}))
+	})
+}
+
+func Test_RemovalListenerClearAfterUpdate(t *testing.T) {
+	var m *ttlmap.Map[int, int]
+	var lens []int
+	m, _ = ttlmap.New[int, int](time.Second, 0, ttlmap.WithRemovalListener(func(int, int, ttlmap.RemovalReason) {
+		lens = append(lens, m.Len())
+	}))
+	m.Set(0, 0)
+	m.Set(1, 1)
+	m.Clear()
+	assert.Equal(t, []int{0, 0}, lens)
+	assert.Zero(t, m.Weight())
+}
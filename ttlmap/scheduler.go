@@ -0,0 +1,120 @@
+package ttlmap
+
+import (
+	"sync"
+	"time"
+
+	"github.com/ddirect/container/heap"
+)
+
+// Scheduler is a Clock which multiplexes all the timers created with AfterFunc on a single timer of the base clock, so
+// that many maps can share it with WithScheduler and the number of running timers stays constant. The functions of
+// the timers expiring together, which are the expiration callbacks of the maps, are called in sequence from the same
+// goroutine: the expired items handlers of the maps should not block, as they delay the others.
+type Scheduler struct {
+	base   Clock
+	mutex  sync.Mutex
+	timers *heap.Heap[*scheduledTimer]
+	timer  Timer
+	armed  time.Time // deadline of timer; zero if not armed
+}
+
+type scheduledTimer struct {
+	c    *Scheduler
+	when time.Time
+	f    func()
+	idx  int // index in the heap; -1 if not pending
+}
+
+// NewScheduler creates a Scheduler on top of base; if base is nil, the system clock is used.
+func NewScheduler(base Clock) *Scheduler {
+	if base == nil {
+		base = systemClock{}
+	}
+	return &Scheduler{
+		base: base,
+		timers: heap.New(func(a, b *scheduledTimer) bool {
+			return a.when.Before(b.when)
+		}, func(t *scheduledTimer, i int) {
+			t.idx = i
+		}),
+	}
+}
+
+func (c *Scheduler) Now() time.Time {
+	return c.base.Now()
+}
+
+func (c *Scheduler) AfterFunc(d time.Duration, f func()) Timer {
+	t := &scheduledTimer{c: c, f: f, idx: -1}
+	t.Reset(d)
+	return t
+}
+
+func (c *Scheduler) run() {
+	var due []*scheduledTimer
+	c.mutex.Lock()
+	now := c.base.Now()
+	c.armed = time.Time{}
+	for c.timers.Len() > 0 && !now.Before(c.timers.Get(0).when) {
+		t := c.timers.Pop()
+		t.idx = -1
+		due = append(due, t)
+	}
+	c.rearm(now)
+	c.mutex.Unlock()
+
+	for _, t := range due {
+		t.f()
+	}
+}
+
+// rearm makes the base timer fire at the earliest deadline; it must be called with the lock held.
+func (c *Scheduler) rearm(now time.Time) {
+	if c.timers.Len() == 0 {
+		if !c.armed.IsZero() {
+			c.timer.Stop()
+			c.armed = time.Time{}
+		}
+		return
+	}
+	next := c.timers.Get(0).when
+	if next.Equal(c.armed) {
+		return
+	}
+	if c.timer == nil {
+		c.timer = c.base.AfterFunc(next.Sub(now), c.run)
+	} else {
+		c.timer.Reset(next.Sub(now))
+	}
+	c.armed = next
+}
+
+func (t *scheduledTimer) Stop() bool {
+	c := t.c
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if t.idx < 0 {
+		return false
+	}
+	c.timers.Remove(t.idx)
+	t.idx = -1
+	c.rearm(c.base.Now())
+	return true
+}
+
+func (t *scheduledTimer) Reset(d time.Duration) bool {
+	c := t.c
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	now := c.base.Now()
+	t.when = now.Add(d)
+	active := t.idx >= 0
+	if active {
+		c.timers.Fix(t.idx)
+	} else {
+		c.timers.Push(t)
+	}
+	c.rearm(now)
+	return active
+}
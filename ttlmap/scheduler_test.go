@@ -0,0 +1,49 @@
+package ttlmap_test
+
+import (
+	"iter"
+	"testing"
+	"time"
+
+	"github.com/ddirect/container/ttlmap"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Scheduler(t *testing.T) {
+	const ttl = time.Second
+	c := ttlmap.NewManualClock(time.Unix(1000, 0))
+	s := ttlmap.NewScheduler(c)
+
+	expired := make(map[int][]int)
+	maps := make([]*ttlmap.Map[int, int], 100)
+	for i := range maps {
+		maps[i] = ttlmap.NewAsync(ttl, 0, func(items iter.Seq[ttlmap.Item[int, int]]) {
+			for item := range items {
+				expired[i] = append(expired[i], item.Key())
+			}
+		}, ttlmap.WithScheduler(s))
+	}
+
+	for i, m := range maps {
+		m.Set(i, i)
+		c.Advance(ttl / 200)
+	}
+	assert.Equal(t, 1, c.Pending())
+
+	maps[0].DeleteKey(0)
+	maps[1].Set(1, 1)
+	c.Advance(ttl * 3 / 4)
+	assert.Equal(t, 1, c.Pending())
+	assert.Len(t, expired, 49)
+	assert.Equal(t, []int{2}, expired[2])
+	assert.NotContains(t, expired, 1)
+
+	c.Advance(ttl)
+	assert.Len(t, expired, 99)
+	assert.Equal(t, []int{1}, expired[1])
+	assert.Zero(t, c.Pending())
+
+	for _, m := range maps {
+		m.Close(false)
+	}
+}
@@ -0,0 +1,170 @@
+package ttlmap
+
+import (
+	"fmt"
+	"hash/maphash"
+	"iter"
+	"time"
+)
+
+// ShardedMap spreads the keys across a number of independent SyncMap shards, each one with its own lock and expiration
+// queue, to reduce contention when it is used by many goroutines. The shard timers are multiplexed on a single timer.
+type ShardedMap[K comparable, V any] struct {
+	seed   maphash.Seed
+	shards []*SyncMap[K, V]
+}
+
+// NewSharded creates a ShardedMap with the given number of shards; the other parameters have the same meaning as in
+// NewSync. handleExpired is called separately for each shard having expired items.
+func NewSharded[K comparable, V any](shards int, ttl, accuracy time.Duration, handleExpired func(iter.Seq2[K, V]), opts ...Option) *ShardedMap[K, V] {
+	if shards < 1 {
+		panic(fmt.Errorf("ttlmap: invalid number of shards: %d", shards))
+	}
+	o := makeOptions(opts)
+	if _, ok := o.clock.(*Scheduler); !ok {
+		// the shards share a single timer
+		opts = append(opts[:len(opts):len(opts)], WithScheduler(NewScheduler(o.clock)))
+	}
+
+	s := &ShardedMap[K, V]{
+		seed:   maphash.MakeSeed(),
+		shards: make([]*SyncMap[K, V], shards),
+	}
+	for i := range s.shards {
+		s.shards[i] = NewSync(ttl, accuracy, handleExpired, opts...)
+	}
+	return s
+}
+
+func (s *ShardedMap[K, V]) shard(k K) *SyncMap[K, V] {
+	return s.shards[maphash.Comparable(s.seed, k)%uint64(len(s.shards))]
+}
+
+// Len returns the total number of items. Since the shards are counted one at a time, the result is not a snapshot
+// when the map is used concurrently.
+func (s *ShardedMap[K, V]) Len() int {
+	n := 0
+	for _, shard := range s.shards {
+		n += shard.Len()
+	}
+	return n
+}
+
+// Weight returns the total weight of the shards; WithMaxWeight limits the weight of each shard separately.
+func (s *ShardedMap[K, V]) Weight() int64 {
+	var w int64
+	for _, shard := range s.shards {
+		w += shard.Weight()
+	}
+	return w
+}
+
+func (s *ShardedMap[K, V]) Set(k K, v V) {
+	s.shard(k).Set(k, v)
+}
+
+func (s *ShardedMap[K, V]) SetWithTTL(k K, v V, ttl time.Duration) {
+	s.shard(k).SetWithTTL(k, v, ttl)
+}
+
+func (s *ShardedMap[K, V]) GetOrCreate(k K, create func() V) (V, bool) {
+	return s.shard(k).GetOrCreate(k, create)
+}
+
+func (s *ShardedMap[K, V]) GetOrCreateWithTTL(k K, ttl time.Duration, create func() V) (V, bool) {
+	return s.shard(k).GetOrCreateWithTTL(k, ttl, create)
+}
+
+func (s *ShardedMap[K, V]) Get(k K) (V, bool) {
+	return s.shard(k).Get(k)
+}
+
+func (s *ShardedMap[K, V]) GetNoTouch(k K) (V, bool) {
+	return s.shard(k).GetNoTouch(k)
+}
+
+func (s *ShardedMap[K, V]) Exists(k K) bool {
+	return s.shard(k).Exists(k)
+}
+
+func (s *ShardedMap[K, V]) Delete(k K) bool {
+	return s.shard(k).Delete(k)
+}
+
+func (s *ShardedMap[K, V]) Compute(k K, f func(old V, exists bool) (v V, keep bool)) (V, ComputeResult) {
+	return s.shard(k).Compute(k, f)
+}
+
+func (s *ShardedMap[K, V]) ComputeIfPresent(k K, f func(old V) (v V, keep bool)) (V, ComputeResult) {
+	return s.shard(k).ComputeIfPresent(k, f)
+}
+
+func (s *ShardedMap[K, V]) ComputeIfAbsent(k K, f func() (v V, keep bool)) (V, ComputeResult) {
+	return s.shard(k).ComputeIfAbsent(k, f)
+}
+
+func (s *ShardedMap[K, V]) Touch(k K) bool {
+	return s.shard(k).Touch(k)
+}
+
+func (s *ShardedMap[K, V]) TouchWithTTL(k K, ttl time.Duration) bool {
+	return s.shard(k).TouchWithTTL(k, ttl)
+}
+
+func (s *ShardedMap[K, V]) Clear() {
+	for _, shard := range s.shards {
+		shard.Clear()
+	}
+}
+
+func (s *ShardedMap[K, V]) SetTTL(ttl time.Duration, rescale bool) {
+	for _, shard := range s.shards {
+		shard.SetTTL(ttl, rescale)
+	}
+}
+
+func (s *ShardedMap[K, V]) SetAccuracy(accuracy time.Duration, rescale bool) {
+	for _, shard := range s.shards {
+		shard.SetAccuracy(accuracy, rescale)
+	}
+}
+
+func (s *ShardedMap[K, V]) Pause() {
+	for _, shard := range s.shards {
+		shard.Pause()
+	}
+}
+
+func (s *ShardedMap[K, V]) Resume() {
+	for _, shard := range s.shards {
+		shard.Resume()
+	}
+}
+
+// All returns the content of the map; each shard is snapshotted when the iteration reaches it.
+func (s *ShardedMap[K, V]) All() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		for _, shard := range s.shards {
+			for k, v := range shard.All() {
+				if !yield(k, v) {
+					return
+				}
+			}
+		}
+	}
+}
+
+func (s *ShardedMap[K, V]) Close(flush bool) {
+	for _, shard := range s.shards {
+		shard.Close(flush)
+	}
+}
+
+// Stats returns the sum of the statistics of all the shards.
+func (s *ShardedMap[K, V]) Stats() Stats {
+	var stats Stats
+	for _, shard := range s.shards {
+		stats.add(shard.Stats())
+	}
+	return stats
+}
@@ -0,0 +1,101 @@
+package ttlmap_test
+
+import (
+	"iter"
+	"maps"
+	"sync"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	"github.com/ddirect/container/ttlmap"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ShardedMap(t *testing.T) {
+	const (
+		ttl    = time.Second
+		shards = 16
+		count  = 1000
+	)
+	c := ttlmap.NewManualClock(time.Unix(1000, 0))
+	expired := make(map[int]int)
+	calls := 0
+	s := ttlmap.NewSharded(shards, ttl, ttl/10, func(items iter.Seq2[int, int]) {
+		calls++
+		for k, v := range items {
+			expired[k] = v
+		}
+	}, ttlmap.WithClock(c))
+
+	ref := make(map[int]int)
+	for i := range count {
+		s.Set(i, -i)
+		ref[i] = -i
+	}
+	assert.Equal(t, count, s.Len())
+	assert.Equal(t, ref, maps.Collect(s.All()))
+	// all the shards share one timer
+	assert.Equal(t, 1, c.Pending())
+
+	v, found := s.Get(10)
+	assert.True(t, found)
+	assert.Equal(t, -10, v)
+	assert.True(t, s.Delete(11))
+	s.SetWithTTL(12, 12, 3*ttl)
+	delete(ref, 11)
+	delete(ref, 12)
+
+	c.Advance(ttl / 2)
+	assert.True(t, s.Touch(13))
+	delete(ref, 13)
+	assert.True(t, c.AdvanceToNext())
+	assert.Equal(t, ref, expired)
+	assert.LessOrEqual(t, calls, shards)
+	assert.Equal(t, 1, c.Pending())
+
+	c.Advance(3 * ttl)
+	assert.Zero(t, s.Len())
+	assert.Equal(t, 12, expired[12])
+	assert.Zero(t, c.Pending())
+}
+
+func Test_ShardedMapConcurrentUse(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		const (
+			ttl        = 10 * time.Millisecond
+			goroutines = 8
+			count      = 1000
+		)
+		var mutex sync.Mutex
+		expired := 0
+		s := ttlmap.NewSharded(4, ttl, ttl/10, func(items iter.Seq2[int, int]) {
+			mutex.Lock()
+			defer mutex.Unlock()
+			for k, v := range items {
+				assert.Equal(t, k, v)
+				expired++
+			}
+		})
+
+		var wg sync.WaitGroup
+		for g := range goroutines {
+			wg.Go(func() {
+				for i := range count {
+					k := g*count + i
+					s.Set(k, k)
+					if v, found := s.Get(k - 1); found {
+						assert.Equal(t, k-1, v)
+					}
+					time.Sleep(time.Duration(i%3) * time.Millisecond)
+				}
+			})
+		}
+		wg.Wait()
+		time.Sleep(2 * ttl)
+		assert.Zero(t, s.Len())
+		mutex.Lock()
+		assert.Equal(t, goroutines*count, expired)
+		mutex.Unlock()
+	})
+}
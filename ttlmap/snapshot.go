@@ -0,0 +1,224 @@
+package ttlmap
+
+import (
+	"bufio"
+	"bytes"
+	"encoding"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+// Codec converts keys or values to and from their binary representation, for use with Encoder and Decoder.
+type Codec[T any] interface {
+	// Append appends the binary representation of t to b.
+	Append(b []byte, t T) ([]byte, error)
+	Decode(b []byte) (T, error)
+}
+
+// ErrInvalidSnapshot is returned when decoding data which was not produced by an Encoder.
+var ErrInvalidSnapshot = errors.New("ttlmap: invalid snapshot")
+
+const (
+	snapshotMagic   = "TTLM"
+	snapshotVersion = 2 // version 1 did not store the age
+)
+
+// Encoder writes the content of a map to a stream. Each item is stored together with its remaining lifetime, its
+// time-to-live, if set with one of the WithTTL methods, and its age.
+type Encoder[K comparable, V any] struct {
+	w  io.Writer
+	kc Codec[K]
+	vc Codec[V]
+}
+
+func NewEncoder[K comparable, V any](w io.Writer, kc Codec[K], vc Codec[V]) *Encoder[K, V] {
+	return &Encoder[K, V]{w: w, kc: kc, vc: vc}
+}
+
+func (e *Encoder[K, V]) Encode(m *Map[K, V]) error {
+	buf := append([]byte(snapshotMagic), snapshotVersion)
+	buf = binary.AppendUvarint(buf, uint64(m.Len()))
+	if _, err := e.w.Write(buf); err != nil {
+		return err
+	}
+	now := m.now()
+	var err error
+	for item := range m.All() {
+		buf = binary.AppendUvarint(buf[:0], uint64(max(item.Rank()-now, 0)))
+		buf = binary.AppendUvarint(buf, uint64(item.entry().ttl))
+		buf = binary.AppendUvarint(buf, uint64(max(now-item.entry().created, 0)))
+		if buf, err = appendField(buf, e.kc, item.Key()); err != nil {
+			return fmt.Errorf("ttlmap: encoding key: %w", err)
+		}
+		if buf, err = appendField(buf, e.vc, *item.Value()); err != nil {
+			return fmt.Errorf("ttlmap: encoding value: %w", err)
+		}
+		if _, err = e.w.Write(buf); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// appendField appends t prefixed by its length.
+func appendField[T any](b []byte, c Codec[T], t T) ([]byte, error) {
+	n := len(b)
+	b = binary.BigEndian.AppendUint32(b, 0)
+	b, err := c.Append(b, t)
+	if err != nil {
+		return nil, err
+	}
+	binary.BigEndian.PutUint32(b[n:], uint32(len(b)-n-4))
+	return b, nil
+}
+
+// Decoder reads the content of a map written by an Encoder.
+type Decoder[K comparable, V any] struct {
+	r  *bufio.Reader
+	kc Codec[K]
+	vc Codec[V]
+}
+
+func NewDecoder[K comparable, V any](r io.Reader, kc Codec[K], vc Codec[V]) *Decoder[K, V] {
+	return &Decoder[K, V]{r: bufio.NewReader(r), kc: kc, vc: vc}
+}
+
+// Decode stores the decoded items in m, replacing the existing items with the same keys. Each item keeps the
+// remaining lifetime and the age it had when it was encoded, so the time spent between encoding and decoding does not
+// count.
+func (d *Decoder[K, V]) Decode(m *Map[K, V]) error {
+	m.checkOpen()
+	header := make([]byte, len(snapshotMagic)+1)
+	if _, err := io.ReadFull(d.r, header); err != nil {
+		return d.fail(err)
+	}
+	version := header[len(snapshotMagic)]
+	if string(header[:len(snapshotMagic)]) != snapshotMagic || version < 1 || version > snapshotVersion {
+		return ErrInvalidSnapshot
+	}
+	count, err := binary.ReadUvarint(d.r)
+	if err != nil {
+		return d.fail(err)
+	}
+	var buf []byte
+	for range count {
+		remaining, err := binary.ReadUvarint(d.r)
+		if err != nil {
+			return d.fail(err)
+		}
+		ttl, err := binary.ReadUvarint(d.r)
+		if err != nil {
+			return d.fail(err)
+		}
+		var age uint64
+		if version > 1 {
+			if age, err = binary.ReadUvarint(d.r); err != nil {
+				return d.fail(err)
+			}
+		}
+		var k K
+		if k, buf, err = readField(d.r, d.kc, buf); err != nil {
+			return d.fail(err)
+		}
+		var v V
+		if v, buf, err = readField(d.r, d.vc, buf); err != nil {
+			return d.fail(err)
+		}
+		m.restore(k, v, timestamp(remaining), timestamp(ttl), timestamp(age))
+	}
+	return nil
+}
+
+func (d *Decoder[K, V]) fail(err error) error {
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return fmt.Errorf("%w: %w", ErrInvalidSnapshot, err)
+	}
+	return err
+}
+
+func readField[T any](r *bufio.Reader, c Codec[T], buf []byte) (t T, _ []byte, err error) {
+	var size [4]byte
+	if _, err = io.ReadFull(r, size[:]); err != nil {
+		return
+	}
+	size32 := binary.BigEndian.Uint32(size[:])
+	if size32 > math.MaxInt32 {
+		// it would not fit an int on 32-bit platforms
+		err = ErrInvalidSnapshot
+		return
+	}
+	n := int(size32)
+	if cap(buf) >= n {
+		buf = buf[:n]
+		if _, err = io.ReadFull(r, buf); err != nil {
+			return
+		}
+	} else {
+		// the buffer grows with the data actually read, so that a corrupted size fails cheaply
+		b := bytes.NewBuffer(buf[:0])
+		if _, err = io.CopyN(b, r, int64(n)); err != nil {
+			return
+		}
+		buf = b.Bytes()
+	}
+	t, err = c.Decode(buf)
+	return t, buf, err
+}
+
+// restore inserts an item with the given remaining lifetime and age.
+func (m *Map[K, V]) restore(k K, v V, remaining timestamp, ttl timestamp, age timestamp) {
+	now := m.now()
+	item, found := m.getOrCreate(k, ttl, now)
+	item.entry().ttl = ttl
+	item.entry().created = now - age
+	m.store(item, found, v)
+	rank := m.capRank(item, now+remaining)
+	m.m.SetRank(item.MapItem, rank)
+	m.checkTimer(rank+m.accuracyH, now)
+}
+
+type binaryCodec[T any] struct{}
+
+// BinaryCodec returns a Codec for types implementing encoding.BinaryMarshaler, with a pointer to them implementing
+// encoding.BinaryUnmarshaler. Other types result in an error when encoding or decoding.
+func BinaryCodec[T any]() Codec[T] {
+	return binaryCodec[T]{}
+}
+
+func (binaryCodec[T]) Append(b []byte, t T) ([]byte, error) {
+	switch m := any(t).(type) {
+	case encoding.BinaryAppender:
+		return m.AppendBinary(b)
+	case encoding.BinaryMarshaler:
+		data, err := m.MarshalBinary()
+		return append(b, data...), err
+	}
+	return nil, fmt.Errorf("ttlmap: %T does not implement encoding.BinaryMarshaler", t)
+}
+
+func (binaryCodec[T]) Decode(b []byte) (t T, err error) {
+	u, ok := any(&t).(encoding.BinaryUnmarshaler)
+	if !ok {
+		return t, fmt.Errorf("ttlmap: %T does not implement encoding.BinaryUnmarshaler", &t)
+	}
+	err = u.UnmarshalBinary(b)
+	return
+}
+
+// MarshalBinary encodes the map using BinaryCodec for keys and values.
+func (m *Map[K, V]) MarshalBinary() ([]byte, error) {
+	var b bytes.Buffer
+	if err := NewEncoder(&b, BinaryCodec[K](), BinaryCodec[V]()).Encode(m); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// UnmarshalBinary decodes data produced by MarshalBinary into the map, which must have been created with one of
+// the New functions.
+func (m *Map[K, V]) UnmarshalBinary(data []byte) error {
+	return NewDecoder(bytes.NewReader(data), BinaryCodec[K](), BinaryCodec[V]()).Decode(m)
+}
@@ -0,0 +1,150 @@
+package ttlmap_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"iter"
+	"math"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/ddirect/container/ttlmap"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type intCodec struct{}
+
+func (intCodec) Append(b []byte, v int) ([]byte, error) {
+	return binary.AppendVarint(b, int64(v)), nil
+}
+
+func (intCodec) Decode(b []byte) (int, error) {
+	v, n := binary.Varint(b)
+	if n != len(b) {
+		return 0, errors.New("invalid int")
+	}
+	return int(v), nil
+}
+
+type stringCodec struct{}
+
+func (stringCodec) Append(b []byte, v string) ([]byte, error) {
+	return append(b, v...), nil
+}
+
+func (stringCodec) Decode(b []byte) (string, error) {
+	return string(b), nil
+}
+
+func Test_Snapshot(t *testing.T) {
+	const ttl = time.Second
+	c := ttlmap.NewManualClock(time.Unix(1000, 0))
+	m, _ := ttlmap.New[int, string](ttl, 0, ttlmap.WithClock(c))
+	m.Set(0, "zero")
+	c.Advance(ttl / 4)
+	m.Set(1, "one")
+	m.SetWithTTL(2, "two", 3*ttl)
+	c.Advance(ttl / 4)
+
+	var b bytes.Buffer
+	require.NoError(t, ttlmap.NewEncoder(&b, intCodec{}, stringCodec{}).Encode(m))
+
+	// restore much later, in a map with a different default ttl
+	c2 := ttlmap.NewManualClock(time.Unix(5000, 0))
+	expired := make(map[int]time.Duration)
+	m2 := ttlmap.NewAsync(2*ttl, 0, func(items iter.Seq[ttlmap.Item[int, string]]) {
+		for item := range items {
+			expired[item.Key()] = c2.Now().Sub(time.Unix(5000, 0))
+		}
+	}, ttlmap.WithClock(c2))
+	m2.Set(1, "replaced")
+	require.NoError(t, ttlmap.NewDecoder(&b, intCodec{}, stringCodec{}).Decode(m2))
+	assert.Equal(t, 3, m2.Len())
+	assert.Equal(t, "one", *m2.GetNoTouch(1).Value())
+
+	c2.Advance(ttl)
+	assert.Equal(t, map[int]time.Duration{0: ttl / 2, 1: ttl * 3 / 4}, expired)
+
+	// the item ttl is preserved
+	m2.Touch(m2.GetNoTouch(2))
+	c2.Advance(3 * ttl)
+	assert.Equal(t, map[int]time.Duration{0: ttl / 2, 1: ttl * 3 / 4, 2: 4 * ttl}, expired)
+}
+
+func Test_SnapshotEmptyAndInvalid(t *testing.T) {
+	m, _ := ttlmap.New[int, string](time.Second, 0)
+	var b bytes.Buffer
+	require.NoError(t, ttlmap.NewEncoder(&b, intCodec{}, stringCodec{}).Encode(m))
+	data := b.Bytes()
+	require.NoError(t, ttlmap.NewDecoder(bytes.NewReader(data), intCodec{}, stringCodec{}).Decode(m))
+	assert.Zero(t, m.Len())
+
+	data[0] = 'X'
+	err := ttlmap.NewDecoder(bytes.NewReader(data), intCodec{}, stringCodec{}).Decode(m)
+	assert.ErrorIs(t, err, ttlmap.ErrInvalidSnapshot)
+
+	m.Set(1, "one")
+	b.Reset()
+	require.NoError(t, ttlmap.NewEncoder(&b, intCodec{}, stringCodec{}).Encode(m))
+	err = ttlmap.NewDecoder(bytes.NewReader(b.Bytes()[:b.Len()-1]), intCodec{}, stringCodec{}).Decode(m)
+	assert.ErrorIs(t, err, ttlmap.ErrInvalidSnapshot)
+}
+
+type binaryKey struct {
+	id uint32
+}
+
+func (k binaryKey) MarshalBinary() ([]byte, error) {
+	return binary.BigEndian.AppendUint32(nil, k.id), nil
+}
+
+func (k *binaryKey) UnmarshalBinary(b []byte) error {
+	if len(b) != 4 {
+		return errors.New("invalid key")
+	}
+	k.id = binary.BigEndian.Uint32(b)
+	return nil
+}
+
+func Test_MarshalBinary(t *testing.T) {
+	const ttl = time.Second
+	c := ttlmap.NewManualClock(time.Unix(1000, 0))
+	m, _ := ttlmap.New[binaryKey, time.Time](ttl, 0, ttlmap.WithClock(c))
+	m.Set(binaryKey{1}, time.Unix(1, 0).UTC())
+	m.Set(binaryKey{2}, time.Unix(2, 0).UTC())
+	data, err := m.MarshalBinary()
+	require.NoError(t, err)
+
+	m2, _ := ttlmap.New[binaryKey, time.Time](ttl, 0, ttlmap.WithClock(c))
+	require.NoError(t, m2.UnmarshalBinary(data))
+	assert.Equal(t, 2, m2.Len())
+	assert.Equal(t, time.Unix(2, 0).UTC(), *m2.GetNoTouch(binaryKey{2}).Value())
+
+	m3, _ := ttlmap.New[int, int](ttl, 0)
+	m3.Set(1, 1)
+	_, err = m3.MarshalBinary()
+	assert.Error(t, err)
+}
+
+func Test_SnapshotCorruptedSize(t *testing.T) {
+	m, _ := ttlmap.New[int, string](time.Second, 0)
+	m.Set(1, "one")
+	var b bytes.Buffer
+	require.NoError(t, ttlmap.NewEncoder(&b, intCodec{}, stringCodec{}).Encode(m))
+	// sizes above math.MaxInt32 would be negative on 32-bit platforms
+	for _, size := range []uint32{math.MaxInt32, math.MaxInt32 + 1, math.MaxUint32} {
+		data := bytes.Clone(b.Bytes())
+		// the size of the value, before its last 3 bytes
+		binary.BigEndian.PutUint32(data[len(data)-7:], size)
+
+		var before, after runtime.MemStats
+		runtime.ReadMemStats(&before)
+		err := ttlmap.NewDecoder(bytes.NewReader(data), intCodec{}, stringCodec{}).Decode(m)
+		runtime.ReadMemStats(&after)
+		assert.ErrorIs(t, err, ttlmap.ErrInvalidSnapshot, "size %d", size)
+		assert.Less(t, after.TotalAlloc-before.TotalAlloc, uint64(1<<20), "size %d", size)
+	}
+}
@@ -0,0 +1,97 @@
+package ttlmap
+
+import "sync/atomic"
+
+// Stats contains the counters collected by a map created with the WithStats option.
+type Stats struct {
+	Gets          uint64 // calls to Get and GetOrCreate
+	Hits          uint64 // gets finding the key
+	Misses        uint64 // gets not finding the key
+	Inserts       uint64 // items added to the map
+	Touches       uint64 // calls to Touch
+	Deletes       uint64 // items removed with Delete or DeleteKey
+	Expirations   uint64 // expired items removed after being delivered to the handler
+	Evictions     uint64 // items evicted to respect the maximum length or the maximum weight
+	Resurrections uint64 // expired items touched by the handler, and thus not removed
+	TimerFirings  uint64
+}
+
+func (s *Stats) add(o Stats) {
+	s.Gets += o.Gets
+	s.Hits += o.Hits
+	s.Misses += o.Misses
+	s.Inserts += o.Inserts
+	s.Touches += o.Touches
+	s.Deletes += o.Deletes
+	s.Expirations += o.Expirations
+	s.Evictions += o.Evictions
+	s.Resurrections += o.Resurrections
+	s.TimerFirings += o.TimerFirings
+}
+
+// counters is nil when the statistics are disabled. Only timerFirings is updated by the timer goroutine.
+type counters struct {
+	Stats
+	timerFirings atomic.Uint64
+}
+
+func (c *counters) snapshot() (s Stats) {
+	if c != nil {
+		s = c.Stats
+		s.TimerFirings = c.timerFirings.Load()
+	}
+	return
+}
+
+func (c *counters) get(found bool) {
+	if c != nil {
+		c.Gets++
+		if found {
+			c.Hits++
+		} else {
+			c.Misses++
+		}
+	}
+}
+
+func (c *counters) insert() {
+	if c != nil {
+		c.Inserts++
+	}
+}
+
+func (c *counters) touch() {
+	if c != nil {
+		c.Touches++
+	}
+}
+
+func (c *counters) delete() {
+	if c != nil {
+		c.Deletes++
+	}
+}
+
+func (c *counters) expire() {
+	if c != nil {
+		c.Expirations++
+	}
+}
+
+func (c *counters) evict() {
+	if c != nil {
+		c.Evictions++
+	}
+}
+
+func (c *counters) resurrect() {
+	if c != nil {
+		c.Resurrections++
+	}
+}
+
+func (c *counters) timerFired() {
+	if c != nil {
+		c.timerFirings.Add(1)
+	}
+}
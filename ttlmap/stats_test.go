@@ -0,0 +1,57 @@
+package ttlmap_test
+
+import (
+	"iter"
+	"testing"
+	"time"
+
+	"github.com/ddirect/container/ttlmap"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Stats(t *testing.T) {
+	const ttl = time.Second
+	c := ttlmap.NewManualClock(time.Unix(1000, 0))
+	var m *ttlmap.Map[int, int]
+	m = ttlmap.NewAsync(ttl, 0, func(items iter.Seq[ttlmap.Item[int, int]]) {
+		for item := range items {
+			if item.Key() == 10 {
+				m.Touch(item)
+			}
+		}
+	}, ttlmap.WithClock(c), ttlmap.WithStats(), ttlmap.WithMaxLen(4))
+
+	m.Set(0, 0)
+	m.Set(1, 1)
+	m.Set(0, 0)
+	m.GetOrCreate(2)
+	m.GetOrCreate(2)
+	m.Get(3)
+	m.Get(1)
+	m.Touch(m.GetNoTouch(1))
+	m.DeleteKey(1)
+	m.DeleteKey(1)
+	m.Delete(m.GetNoTouch(2))
+	for i := range 4 {
+		m.Set(10+i, i)
+	}
+	c.Advance(ttl)
+
+	assert.Equal(t, ttlmap.Stats{
+		Gets:          4,
+		Hits:          2,
+		Misses:        2,
+		Inserts:       7,
+		Touches:       2,
+		Deletes:       2,
+		Expirations:   3,
+		Evictions:     1,
+		Resurrections: 1,
+		TimerFirings:  2,
+	}, m.Stats())
+
+	m2, _ := ttlmap.New[int, int](ttl, 0)
+	m2.Get(0)
+	m2.Set(0, 0)
+	assert.Zero(t, m2.Stats())
+}
@@ -0,0 +1,235 @@
+package ttlmap
+
+import (
+	"iter"
+	"sync"
+	"time"
+)
+
+// SyncMap is a Map which can be used concurrently from different goroutines. Items are never exposed: keys and values
+// are copied in and out of the map while holding its lock.
+type SyncMap[K comparable, V any] struct {
+	mutex sync.Mutex
+	m     *Map[K, V]
+}
+
+type keyValue[K comparable, V any] struct {
+	key   K
+	value V
+}
+
+// NewSync creates a new SyncMap; ttl, accuracy and opts have the same meaning as in New. The expired items are removed
+// from the map while holding the lock, then handleExpired is called without holding it, so it is allowed to call any
+// SyncMap method. As a consequence, expired items cannot be kept alive by touching them. handleExpired can be nil.
+func NewSync[K comparable, V any](ttl, accuracy time.Duration, handleExpired func(iter.Seq2[K, V]), opts ...Option) *SyncMap[K, V] {
+	s := &SyncMap[K, V]{}
+	s.m = NewAsync(ttl, accuracy, func(items iter.Seq[Item[K, V]]) {
+		var expired []keyValue[K, V]
+		s.mutex.Lock()
+		for item := range items {
+			expired = append(expired, keyValue[K, V]{item.Key(), *item.Value()})
+		}
+		s.mutex.Unlock()
+		if handleExpired != nil && len(expired) > 0 {
+			handleExpired(pairs(expired))
+		}
+	}, opts...)
+	return s
+}
+
+func (s *SyncMap[K, V]) Len() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.m.Len()
+}
+
+func (s *SyncMap[K, V]) Weight() int64 {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.m.Weight()
+}
+
+func (s *SyncMap[K, V]) Set(k K, v V) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.m.Set(k, v)
+}
+
+func (s *SyncMap[K, V]) setDefault(k K, v V) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.m.setDefault(k, v)
+}
+
+func (s *SyncMap[K, V]) SetWithTTL(k K, v V, ttl time.Duration) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.m.SetWithTTL(k, v, ttl)
+}
+
+// GetOrCreate returns the value stored under k; if k does not exist, the value returned by create is stored and
+// returned. create is called while holding the lock.
+func (s *SyncMap[K, V]) GetOrCreate(k K, create func() V) (V, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.m.getOrCreateValue(k, 0, create)
+}
+
+func (s *SyncMap[K, V]) GetOrCreateWithTTL(k K, ttl time.Duration, create func() V) (V, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.m.getOrCreateValue(k, itemTTL(ttl), create)
+}
+
+func (s *SyncMap[K, V]) Get(k K) (V, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return value(s.m.Get(k))
+}
+
+func (s *SyncMap[K, V]) GetNoTouch(k K) (V, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return value(s.m.GetNoTouch(k))
+}
+
+// getRemaining is like Get, but it also returns the remaining lifetime of the item before touching it.
+func (s *SyncMap[K, V]) getRemaining(k K) (v V, remaining timestamp, found bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if item := s.m.GetNoTouch(k); item.Present() {
+		remaining = item.Rank() - s.m.now()
+	}
+	v, found = value(s.m.Get(k))
+	return
+}
+
+func (s *SyncMap[K, V]) Exists(k K) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.m.Exists(k)
+}
+
+func (s *SyncMap[K, V]) Delete(k K) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.m.DeleteKey(k)
+}
+
+// Compute works like Map.Compute and returns the resulting value, which is the zero value if the item is not present.
+// f is called with the lock held, so it must not call any method of the map.
+func (s *SyncMap[K, V]) Compute(k K, f func(old V, exists bool) (v V, keep bool)) (V, ComputeResult) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return computed(s.m.Compute(k, f))
+}
+
+func (s *SyncMap[K, V]) ComputeIfPresent(k K, f func(old V) (v V, keep bool)) (V, ComputeResult) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return computed(s.m.ComputeIfPresent(k, f))
+}
+
+func (s *SyncMap[K, V]) ComputeIfAbsent(k K, f func() (v V, keep bool)) (V, ComputeResult) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return computed(s.m.ComputeIfAbsent(k, f))
+}
+
+func (s *SyncMap[K, V]) Touch(k K) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	item := s.m.GetNoTouch(k)
+	if item.Present() {
+		s.m.Touch(item)
+	}
+	return item.Present()
+}
+
+func (s *SyncMap[K, V]) TouchWithTTL(k K, ttl time.Duration) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	item := s.m.GetNoTouch(k)
+	if item.Present() {
+		s.m.TouchWithTTL(item, ttl)
+	}
+	return item.Present()
+}
+
+func (s *SyncMap[K, V]) Clear() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.m.Clear()
+}
+
+func (s *SyncMap[K, V]) SetTTL(ttl time.Duration, rescale bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.m.SetTTL(ttl, rescale)
+}
+
+func (s *SyncMap[K, V]) SetAccuracy(accuracy time.Duration, rescale bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.m.SetAccuracy(accuracy, rescale)
+}
+
+func (s *SyncMap[K, V]) Pause() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.m.Pause()
+}
+
+func (s *SyncMap[K, V]) Resume() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.m.Resume()
+}
+
+// All returns a snapshot of the map content taken when the iteration starts.
+func (s *SyncMap[K, V]) All() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		s.mutex.Lock()
+		all := make([]keyValue[K, V], 0, s.m.Len())
+		for item := range s.m.All() {
+			all = append(all, keyValue[K, V]{item.Key(), *item.Value()})
+		}
+		s.mutex.Unlock()
+		pairs(all)(yield)
+	}
+}
+
+func value[K comparable, V any](item Item[K, V]) (v V, found bool) {
+	if item.Present() {
+		v, found = *item.Value(), true
+	}
+	return
+}
+
+func computed[K comparable, V any](item Item[K, V], r ComputeResult) (V, ComputeResult) {
+	v, _ := value(item)
+	return v, r
+}
+
+func pairs[K comparable, V any](s []keyValue[K, V]) iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		for _, p := range s {
+			if !yield(p.key, p.value) {
+				return
+			}
+		}
+	}
+}
+
+// Close closes the underlying Map; see Map.Close. When flushing, handleExpired receives the remaining items.
+func (s *SyncMap[K, V]) Close(flush bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.m.Close(flush)
+}
+
+func (s *SyncMap[K, V]) Stats() Stats {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.m.Stats()
+}
@@ -0,0 +1,100 @@
+package ttlmap_test
+
+import (
+	"iter"
+	"maps"
+	"sync"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	"github.com/ddirect/container/ttlmap"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_SyncMap(t *testing.T) {
+	const ttl = time.Second
+	c := ttlmap.NewManualClock(time.Unix(1000, 0))
+	var s *ttlmap.SyncMap[int, string]
+	expired := make(map[int]string)
+	s = ttlmap.NewSync(ttl, 0, func(items iter.Seq2[int, string]) {
+		for k, v := range items {
+			expired[k] = v
+			// the lock is not held here
+			assert.False(t, s.Exists(k))
+			s.Set(k+100, v)
+		}
+	}, ttlmap.WithClock(c))
+
+	s.Set(1, "a")
+	s.SetWithTTL(2, "b", 2*ttl)
+	v, found := s.GetOrCreate(3, func() string { return "c" })
+	assert.False(t, found)
+	assert.Equal(t, "c", v)
+	v, found = s.GetOrCreate(3, func() string { panic("unexpected call") })
+	assert.True(t, found)
+	assert.Equal(t, "c", v)
+	assert.Equal(t, 3, s.Len())
+
+	c.Advance(ttl / 2)
+	assert.True(t, s.Touch(1))
+	assert.False(t, s.Touch(4))
+	v, found = s.Get(2)
+	assert.True(t, found)
+	assert.Equal(t, "b", v)
+	_, found = s.GetNoTouch(4)
+	assert.False(t, found)
+
+	c.Advance(ttl / 2)
+	assert.Equal(t, map[int]string{3: "c"}, expired)
+	assert.Equal(t, map[int]string{1: "a", 2: "b", 103: "c"}, maps.Collect(s.All()))
+
+	assert.True(t, s.Delete(103))
+	assert.False(t, s.Delete(103))
+	assert.True(t, s.TouchWithTTL(2, ttl/4))
+	c.Advance(ttl / 2)
+	assert.Equal(t, map[int]string{1: "a", 2: "b", 3: "c"}, expired)
+
+	s.Clear()
+	assert.Zero(t, s.Len())
+}
+
+func Test_SyncMapConcurrentUse(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		const (
+			ttl        = 10 * time.Millisecond
+			goroutines = 8
+			count      = 1000
+		)
+		var mutex sync.Mutex
+		expired := 0
+		s := ttlmap.NewSync(ttl, ttl/10, func(items iter.Seq2[int, int]) {
+			for k, v := range items {
+				assert.Equal(t, k, v)
+				mutex.Lock()
+				expired++
+				mutex.Unlock()
+			}
+		})
+
+		var wg sync.WaitGroup
+		for g := range goroutines {
+			wg.Go(func() {
+				for i := range count {
+					k := g*count + i
+					s.Set(k, k)
+					if v, found := s.Get(k - 1); found {
+						assert.Equal(t, k-1, v)
+					}
+					time.Sleep(time.Duration(i%3) * time.Millisecond)
+				}
+			})
+		}
+		wg.Wait()
+		time.Sleep(2 * ttl)
+		assert.Zero(t, s.Len())
+		mutex.Lock()
+		assert.Equal(t, goroutines*count, expired)
+		mutex.Unlock()
+	})
+}
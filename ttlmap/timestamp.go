@@ -20,6 +20,6 @@ func fromTime(t time.Time) timestamp {
 	return timestamp(t.UnixNano())
 }
 
-func getNow() timestamp {
-	return fromTime(time.Now())
+func toTime(t timestamp) time.Time {
+	return time.Unix(0, int64(t))
 }
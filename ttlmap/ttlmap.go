@@ -1,80 +1,301 @@
 package ttlmap
 
 import (
+	"cmp"
+	"errors"
 	"fmt"
 	"iter"
+	"math"
+	"math/rand/v2"
 	"time"
 
+	"github.com/ddirect/container/fifo"
+	"github.com/ddirect/container/internal/notifier"
 	"github.com/ddirect/container/internal/rankedmap"
 )
 
 // ttlmap.Map is a key value store where unused items are automatically removed when they expire. It is not safe to call any method concurrently
 // from different goroutines. This includes iterating on the expired items sequence.
 type Map[K comparable, V any] struct {
-	m            *rankedmap.Map[K, timestamp, V]
+	m            *rankedmap.Map[K, timestamp, entry[V]]
 	ttl          timestamp
 	accuracyH    timestamp // accuracy/2
+	clock        Clock
 	queueCleanup func()
-	timer        *time.Timer
+	timer        Timer
+	deadline     timestamp // when timer fires
+	maxLen       int
+	afterWrite   bool                  // reads do not refresh the items
+	evicted      fifo.Fifo[Item[K, V]] // evicted items not yet delivered
+	closed       bool
+	flushing     bool   // the remaining items are delivered by the next cleanup
+	onClose      func() // called when the map is completely closed
+	stats        *counters
+	onRemove     RemovalListener[K, V]
+	manual       bool // there is no timer: the expiration is driven by Expire
+	weigher      Weigher[K, V]
+	maxWeight    int64
+	weight       int64
+	maxAge       timestamp // 0 when disabled
+	wheel        bool
+	policy       ExpiryPolicy[K, V]
+	jitter       float64 // 0 when disabled
+	paused       bool
+	pausedAt     timestamp
+	expiring     timestamp                                 // time of the running cleanup, for Decide; 0 otherwise
+	delivering   rankedmap.MapItem[K, timestamp, entry[V]] // expired item being delivered, which cannot be evicted
 }
 
+// ErrClosed is the panic value raised when a closed map is modified.
+var ErrClosed = errors.New("ttlmap: map is closed")
+
 // New creates a new ttlmap. ttl sets the minimum lifetime of each item. ttl must be at least 1ms; accuracy defines how much the item
 // lifetime is allowed to be extended to avoid resetting the expiration timer. It must be less than ttl and can be 0.
 // This version returns the map instance and a channel where item iterators are received. The iterators provide a notification on
 // which items are expired. Iterating through the items is required in order for the items to be removed from the map.
-func New[K comparable, V any](ttl, accuracy time.Duration) (*Map[K, V], <-chan iter.Seq[Item[K, V]]) {
-	expired := make(chan iter.Seq[Item[K, V]])
-	return NewAsync(ttl, accuracy, func(items iter.Seq[Item[K, V]]) {
-		expired <- items
-	}), expired
+// opts can be used to customize the map further. The channel is closed by Close.
+func New[K comparable, V any](ttl, accuracy time.Duration, opts ...Option) (*Map[K, V], <-chan iter.Seq[Item[K, V]]) {
+	expired := notifier.New[iter.Seq[Item[K, V]]]()
+	opts = append(opts[:len(opts):len(opts)], WithCloseListener(expired.Close))
+	return NewAsync(ttl, accuracy, expired.Send, opts...), expired.C()
 }
 
 // NewAsync is like New, but instead of returning a channel, it gets a method which is called when items expire.
 // Note that the returned iterator must not be used concurrently with other ttlmap methods, so proper syncrhonization
-// must still be ensured externally.
-func NewAsync[K comparable, V any](ttl, accuracy time.Duration, handleExpired func(iter.Seq[Item[K, V]])) *Map[K, V] {
-	if ttl < time.Millisecond {
-		panic(fmt.Errorf("ttlmap: invalid time-to-live: %v", ttl))
+// must still be ensured externally. The handler can keep an expired item by calling Decide before moving to the next
+// one.
+func NewAsync[K comparable, V any](ttl, accuracy time.Duration, handleExpired func(iter.Seq[Item[K, V]]), opts ...Option) *Map[K, V] {
+	m := newMap[K, V](ttl, accuracy, opts)
+	cleanup := func(yield func(Item[K, V]) bool) {
+		m.cleanup(m.now(), yield)
 	}
-	if accuracy >= ttl || accuracy < 0 {
-		panic(fmt.Errorf("ttlmap: invalid accuracy %v for ttl %v", accuracy, ttl))
+
+	// defining this here saves an allocation in the AfterFunc call
+	m.queueCleanup = func() {
+		m.stats.timerFired()
+		handleExpired(cleanup)
 	}
 
+	return m
+}
+
+// NewManual creates a map without timer, for use in event loops: the owner of the map is responsible for calling
+// Expire, no later than the time returned by NextDeadline. No background goroutines are involved.
+func NewManual[K comparable, V any](ttl, accuracy time.Duration, opts ...Option) *Map[K, V] {
+	m := newMap[K, V](ttl, accuracy, opts)
+	m.manual = true
+	return m
+}
+
+func newMap[K comparable, V any](ttl, accuracy time.Duration, opts []Option) *Map[K, V] {
+	checkParams(ttl, accuracy)
+	o := makeOptions(opts)
 	m := &Map[K, V]{
-		m:         rankedmap.New[K, timestamp, V](),
-		ttl:       fromDuration(ttl),
-		accuracyH: fromDuration(accuracy / 2),
+		ttl:        fromDuration(ttl),
+		accuracyH:  fromDuration(accuracy / 2),
+		clock:      o.clock,
+		maxLen:     o.maxLen,
+		onClose:    o.onClose,
+		afterWrite: o.afterWrite,
+		maxAge:     fromDuration(o.maxAge),
+		jitter:     o.jitter,
+	}
+	if o.wheel {
+		checkWheelAccuracy(accuracy)
+		m.m = rankedmap.NewWheel[K, timestamp, entry[V]](fromDuration(accuracy / 2))
+		m.wheel = true
+	} else {
+		m.m = rankedmap.New[K, timestamp, entry[V]]()
+	}
+	if o.stats {
+		m.stats = &counters{}
+	}
+	if o.onRemove != nil {
+		onRemove, ok := o.onRemove.(RemovalListener[K, V])
+		if !ok {
+			panic(fmt.Errorf("ttlmap: %T does not match the map types", o.onRemove))
+		}
+		m.onRemove = onRemove
+	}
+	if o.policy != nil {
+		policy, ok := o.policy.(ExpiryPolicy[K, V])
+		if !ok {
+			panic(fmt.Errorf("ttlmap: %T does not match the map types", o.policy))
+		}
+		m.policy = policy
+	}
+	if o.weigher != nil {
+		weigher, ok := o.weigher.(Weigher[K, V])
+		if !ok {
+			panic(fmt.Errorf("ttlmap: %T does not match the map types", o.weigher))
+		}
+		m.weigher = weigher
+		m.maxWeight = o.maxWeight
 	}
 
-	cleanup := func(yield func(Item[K, V]) bool) {
-		now := getNow()
+	return m
+}
+
+// Close stops the expiration timer and makes the map read only: any further attempt to modify it panics with
+// ErrClosed. If flush is true, all the remaining items are delivered to the expired items handler, as if they
+// were expired, in one last call issued by the timer goroutine; otherwise they are left in the map. The channel
+// returned by New is closed after the last delivery, or immediately when not flushing.
+func (m *Map[K, V]) Close(flush bool) {
+	m.checkOpen()
+	m.closed = true
+	m.paused = false // the timer is needed for flushing and the ranks are no longer relevant
+	if flush && (m.Len() > 0 || m.evicted.Len() > 0) {
+		m.flushing = true
+		now := m.now()
+		m.checkTimer(now, now)
+		return
+	}
+	if m.timer != nil {
+		m.timer.Stop()
+	}
+	m.finishClose()
+}
+
+func (m *Map[K, V]) finishClose() {
+	if m.flushing {
+		m.clear()
+		m.flushing = false
+	}
+	m.evicted.Clear()
+	m.timer = nil
+	if m.onClose != nil {
+		m.onClose()
+	}
+}
+
+func (m *Map[K, V]) checkOpen() {
+	if m.closed {
+		panic(ErrClosed)
+	}
+}
+
+// SetTTL changes the time-to-live of the map, with the same constraints as New. Items created or refreshed
+// afterwards use the new value; if rescale is true, the lifetime of the existing items which do not have their own
+// time-to-live is also shifted by the difference, otherwise they keep their current expiration.
+func (m *Map[K, V]) SetTTL(ttl time.Duration, rescale bool) {
+	m.checkOpen()
+	checkParams(ttl, toDuration(2*m.accuracyH))
+	delta := fromDuration(ttl) - m.ttl
+	m.ttl += delta
+	if rescale && delta != 0 {
+		m.m.Rerank(func(item rankedmap.MapItem[K, timestamp, entry[V]]) timestamp {
+			if item.Value().ttl != 0 {
+				return item.Rank()
+			}
+			return m.capRank(wrapItem(item), item.Rank()+delta)
+		})
+		m.rescheduleTimer(m.now())
+	}
+}
+
+// SetAccuracy changes the accuracy of the map, with the same constraints as New. The expiration timer is rescheduled
+// accordingly; if rescale is true, the lifetime of the existing items is also shifted by half the difference, as if
+// they had been refreshed with the new accuracy. With WithTimingWheel, the slots are resized to the new accuracy.
+func (m *Map[K, V]) SetAccuracy(accuracy time.Duration, rescale bool) {
+	m.checkOpen()
+	checkParams(toDuration(m.ttl), accuracy)
+	if m.wheel {
+		checkWheelAccuracy(accuracy)
+		m.m.SetGranularity(fromDuration(accuracy / 2))
+	}
+	delta := fromDuration(accuracy/2) - m.accuracyH
+	m.accuracyH += delta
+	if rescale && delta != 0 {
+		// the shift is uniform, so the order is preserved
+		m.m.Rerank(func(item rankedmap.MapItem[K, timestamp, entry[V]]) timestamp {
+			return m.capRank(wrapItem(item), item.Rank()+delta)
+		})
+	}
+	m.rescheduleTimer(m.now())
+}
+
+// Stats returns the statistics collected so far; they are all zero unless the map was created with WithStats.
+func (m *Map[K, V]) Stats() Stats {
+	return m.stats.snapshot()
+}
+
+func (m *Map[K, V]) cleanup(now timestamp, yield func(Item[K, V]) bool) {
+	if m.closed && !m.flushing {
+		return
+	}
+	for {
+		item, ok := m.evicted.Dequeue()
+		if !ok {
+			break
+		}
+		if !yield(item) {
+			m.restartTimer(now)
+			return
+		}
+	}
+	if m.flushing {
 		for m.m.Len() > 0 {
 			item := m.m.First()
-			// checkTimer expects that there are no items with expiration <= now
-			if now.Before(item.Rank()) {
-				break
-			}
-			if !yield(item) {
+			if !yield(wrapItem(item)) {
 				break
 			}
-			// it the item is touched in the callback, it is not removed
-			if !now.Before(item.Rank()) {
-				m.m.Delete(item)
-			}
+			m.m.Delete(item)
+			m.stats.expire()
+			m.removed(wrapItem(item), ReasonExpired)
+		}
+		m.finishClose()
+		return
+	}
+	m.expiring = now
+	defer func() { m.expiring = 0 }()
+	for item := range m.m.Due(now) {
+		m.delivering = item
+		ok := yield(wrapItem(item))
+		m.delivering = rankedmap.MapItem[K, timestamp, entry[V]]{}
+		if !ok {
+			break
 		}
-		if m.Len() > 0 {
-			m.startTimer(now)
+		// the item is not removed if it was kept with Decide or touched by the handler, or if the handler deleted it
+		if !item.Present() {
+			continue
+		}
+		if !now.Before(item.Rank()) {
+			m.m.Delete(item)
+			m.stats.expire()
+			m.removed(wrapItem(item), ReasonExpired)
 		} else {
-			m.timer = nil // mark the timer as stopped
+			m.stats.resurrect()
 		}
 	}
+	m.restartTimer(now)
+}
 
-	// defining this here saves an allocation in the AfterFunc call
-	m.queueCleanup = func() {
-		handleExpired(cleanup)
+// NextDeadline returns the time by which Expire should be called on a map created with NewManual; it returns false
+// if the map is empty.
+func (m *Map[K, V]) NextDeadline() (time.Time, bool) {
+	switch {
+	case m.evicted.Len() > 0 || m.flushing:
+		return m.clock.Now(), true
+	case m.closed || m.paused || m.Len() == 0:
+		return time.Time{}, false
+	default:
+		return toTime(m.dueDeadline()), true
 	}
+}
 
-	return m
+// Expire returns the items of a map created with NewManual which are expired at the given time; it works like the
+// sequences delivered by New and NewAsync. The lifetime of the items kept with Decide starts from now.
+func (m *Map[K, V]) Expire(now time.Time) iter.Seq[Item[K, V]] {
+	if !m.manual {
+		panic(errors.New("ttlmap: Expire called on a map with timer"))
+	}
+	return func(yield func(Item[K, V]) bool) {
+		t := fromTime(now)
+		if m.paused {
+			t = m.pausedAt
+		}
+		m.cleanup(t, yield)
+	}
 }
 
 func (m *Map[K, V]) NullItem() Item[K, V] {
@@ -85,43 +306,129 @@ func (m *Map[K, V]) Len() int {
 	return m.m.Len()
 }
 
+// Set stores v under k. If k already exists, its value is replaced and its lifetime is refreshed; a time-to-live
+// previously set with SetWithTTL is preserved.
 func (m *Map[K, V]) Set(k K, v V) Item[K, V] {
-	item, _ := m.GetOrCreate(k)
-	*item.Value() = v
+	m.checkOpen()
+	now := m.now()
+	item, found := m.getOrCreate(k, 0, now)
+	m.written(item, found, v, now)
+	m.store(item, found, v)
+	return item
+}
+
+// setDefault is like Set, but an item found with its own time-to-live goes back to the map one.
+func (m *Map[K, V]) setDefault(k K, v V) {
+	m.checkOpen()
+	now := m.now()
+	item, found := m.getOrCreate(k, 0, now)
+	if found && item.entry().ttl != 0 {
+		m.setTTL(item, m.ttl, now)
+		item.entry().ttl = 0
+	}
+	m.written(item, found, v, now)
+	m.store(item, found, v)
+}
+
+// SetWithTTL is like Set, but the item lives for ttl instead of the map time-to-live. The item keeps ttl
+// when it is touched later on.
+func (m *Map[K, V]) SetWithTTL(k K, v V, ttl time.Duration) Item[K, V] {
+	m.checkOpen()
+	t := itemTTL(ttl)
+	now := m.now()
+	item, found := m.getOrCreate(k, t, now)
+	if found {
+		item.entry().created = now
+		m.setTTL(item, t, now)
+	}
+	m.store(item, found, v)
 	return item
 }
 
 func (m *Map[K, V]) GetOrCreate(k K) (Item[K, V], bool) {
-	now := getNow()
-	item, found := m.m.GetOrCreate(k, now+m.ttl+m.accuracyH)
+	m.checkOpen()
+	now := m.now()
+	item, found := m.getOrCreate(k, 0, now)
+	m.stats.get(found)
 	if found {
-		m.refresh(item, now)
+		m.touch(item, now)
+	} else {
+		m.written(item, false, *item.Value(), now)
 	}
-	if m.timer == nil {
-		m.startTimer(now)
+	return item, found
+}
+
+// GetOrCreateWithTTL is like GetOrCreate, but sets the time-to-live of the item to ttl, whether it is
+// created or found.
+func (m *Map[K, V]) GetOrCreateWithTTL(k K, ttl time.Duration) (Item[K, V], bool) {
+	m.checkOpen()
+	t := itemTTL(ttl)
+	now := m.now()
+	item, found := m.getOrCreate(k, t, now)
+	m.stats.get(found)
+	if found {
+		m.setTTL(item, t, now)
 	}
 	return item, found
 }
 
+// getOrCreateValue is like GetOrCreate, or GetOrCreateWithTTL if ttl is not 0, but the value of a new item is
+// created before storing it, so that it is written only once.
+func (m *Map[K, V]) getOrCreateValue(k K, ttl timestamp, create func() V) (V, bool) {
+	m.checkOpen()
+	now := m.now()
+	item, found := m.getOrCreate(k, ttl, now)
+	m.stats.get(found)
+	switch {
+	case found && ttl != 0:
+		m.setTTL(item, ttl, now)
+	case found:
+		m.touch(item, now)
+	default:
+		v := create()
+		if ttl == 0 {
+			m.written(item, false, v, now)
+		}
+		m.store(item, false, v)
+		return v, false
+	}
+	return *item.Value(), true
+}
+
 func (m *Map[K, V]) Delete(item Item[K, V]) {
-	m.m.Delete(item)
+	m.checkOpen()
+	m.delete(item)
 }
 
 func (m *Map[K, V]) DeleteKey(k K) bool {
-	return m.m.DeleteKey(k)
+	m.checkOpen()
+	item := wrapItem(m.m.Get(k))
+	if !item.Present() {
+		return false
+	}
+	m.delete(item)
+	return true
+}
+
+func (m *Map[K, V]) delete(item Item[K, V]) {
+	m.m.Delete(item.MapItem)
+	m.stats.delete()
+	m.removed(item, ReasonDeleted)
 }
 
 func (m *Map[K, V]) Get(k K) Item[K, V] {
-	now := getNow()
-	item := m.m.Get(k)
+	m.checkOpen()
+	now := m.now()
+	item := wrapItem(m.m.Get(k))
+	m.stats.get(item.Present())
 	if item.Present() {
-		m.refresh(item, now)
+		m.touch(item, now)
 	}
 	return item
 }
 
 func (m *Map[K, V]) GetNoTouch(k K) Item[K, V] {
-	return m.m.Get(k)
+	return wrapItem(m.m.Get(k))
 }
 
 func (m *Map[K, V]) Exists(k K) bool {
@@ -129,26 +436,277 @@ func (m *Map[K, V]) Exists(k K) bool {
 }
 
 func (m *Map[K, V]) All() iter.Seq[Item[K, V]] {
-	return m.m.All()
+	return func(yield func(Item[K, V]) bool) {
+		for item := range m.m.All() {
+			if !yield(wrapItem(item)) {
+				return
+			}
+		}
+	}
+}
+
+// Ordered returns the items in expiry order, the first one being the next to expire. Unlike the expired items
+// sequence, it does not remove anything; the map must not be modified during the iteration.
+func (m *Map[K, V]) Ordered() iter.Seq[Item[K, V]] {
+	return func(yield func(Item[K, V]) bool) {
+		for item := range m.m.Ordered() {
+			if !yield(wrapItem(item)) {
+				return
+			}
+		}
+	}
+}
+
+// ExpiresAt returns the time from which the item is considered expired; it is delivered to the expired items
+// handler no later than accuracy/2 after that. While the map is paused, it is the time at which the item would
+// expire if the map was resumed now.
+func (m *Map[K, V]) ExpiresAt(item Item[K, V]) time.Time {
+	if m.paused {
+		return toTime(item.Rank() + fromTime(m.clock.Now()) - m.pausedAt)
+	}
+	return toTime(item.Rank())
+}
+
+// Remaining returns the lifetime left to the item, or 0 if it is already expired.
+func (m *Map[K, V]) Remaining(item Item[K, V]) time.Duration {
+	return m.remaining(item, m.now())
 }
 
 func (m *Map[K, V]) Clear() {
+	m.checkOpen()
+	m.clear()
+}
+
+func (m *Map[K, V]) clear() {
+	var cleared []keyValue[K, V]
+	if m.onRemove != nil {
+		cleared = make([]keyValue[K, V], 0, m.Len())
+		for item := range m.All() {
+			cleared = append(cleared, keyValue[K, V]{item.Key(), *item.Value()})
+		}
+	}
 	m.m.Clear()
+	m.weight = 0
+	// the listener is notified after the map has been updated
+	for _, kv := range cleared {
+		m.onRemove(kv.key, kv.value, ReasonCleared)
+	}
 }
 
 func (m *Map[K, V]) Touch(item Item[K, V]) {
-	m.refresh(item, getNow())
+	m.checkOpen()
+	m.stats.touch()
+	m.touch(item, m.now())
+}
+
+// TouchWithTTL refreshes the item and sets its time-to-live to ttl. Unlike Touch, it can also shorten the
+// remaining lifetime of the item.
+func (m *Map[K, V]) TouchWithTTL(item Item[K, V], ttl time.Duration) {
+	m.checkOpen()
+	m.stats.touch()
+	m.setTTL(item, itemTTL(ttl), m.now())
+}
+
+func (m *Map[K, V]) getOrCreate(k K, ttl timestamp, now timestamp) (Item[K, V], bool) {
+	rank := now + m.accuracyH
+	if ttl != 0 {
+		rank += ttl
+	} else {
+		rank += m.ttl
+	}
+	rank += m.jitterOf(cmp.Or(ttl, m.ttl))
+	if m.maxAge != 0 {
+		rank = min(rank, now+m.maxAge)
+	}
+	if m.maxLen > 0 && m.m.Len() >= m.maxLen && !m.m.Exists(k) {
+		m.evict(now)
+	}
+	mi, found := m.m.GetOrCreate(k, rank)
+	item := wrapItem(mi)
+	if !found {
+		item.entry().ttl = ttl
+		item.entry().created = now
+		m.stats.insert()
+		m.checkTimer(rank+m.accuracyH, now)
+	}
+	return item, found
+}
+
+// evict removes the item closest to expiration and queues it for delivery to the expired items handler.
+// The item being delivered to the handler is skipped; evict returns false if it is the only one.
+func (m *Map[K, V]) evict(now timestamp) bool {
+	first := m.m.First()
+	if first == m.delivering {
+		if m.m.Len() == 1 {
+			return false
+		}
+		// move it out of the way to find the next one
+		rank := first.Rank()
+		m.m.SetRank(first, math.MaxInt64)
+		next := m.m.First()
+		m.m.SetRank(first, rank)
+		first = next
+	}
+	item := wrapItem(first)
+	m.m.Delete(item.MapItem)
+	item.entry().evicted = true
+	m.evicted.Enqueue(item)
+	m.stats.evict()
+	m.removed(item, ReasonEvicted)
+	m.checkTimer(now, now)
+	return true
+}
+
+func (m *Map[K, V]) ttlOf(item Item[K, V]) timestamp {
+	if ttl := item.entry().ttl; ttl != 0 {
+		return ttl
+	}
+	return m.ttl
+}
+
+// touch handles a read access to the item.
+func (m *Map[K, V]) touch(item Item[K, V], now timestamp) {
+	if m.policy != nil {
+		m.applyPolicy(item, m.policy.OnRead(item.Key(), *item.Value(), m.remaining(item, now)), now)
+	} else if !m.afterWrite {
+		m.refresh(item, now)
+	}
+}
+
+// written handles a write access to the item, before v is stored.
+func (m *Map[K, V]) written(item Item[K, V], found bool, v V, now timestamp) {
+	if found {
+		item.entry().created = now
+	}
+	switch {
+	case m.policy == nil:
+		if found {
+			m.refresh(item, now)
+		}
+	case found:
+		m.applyPolicy(item, m.policy.OnUpdate(item.Key(), v, m.remaining(item, now)), now)
+	default:
+		m.applyPolicy(item, m.policy.OnCreate(item.Key(), v), now)
+	}
 }
 
 func (m *Map[K, V]) refresh(item Item[K, V], now timestamp) {
-	if item.Rank().Before(now + m.ttl) {
-		m.m.SetRank(item, now+m.ttl+m.accuracyH)
+	ttl := m.ttlOf(item)
+	if item.Rank().Before(m.capRank(item, now+ttl)) {
+		m.m.SetRank(item.MapItem, m.capRank(item, now+ttl+m.jitterOf(ttl)+m.accuracyH))
+	}
+}
+
+// jitterOf returns a random extension of the lifetime for ttl, below maxJitter(ttl).
+func (m *Map[K, V]) jitterOf(ttl timestamp) timestamp {
+	if n := m.maxJitter(ttl); n > 0 {
+		return timestamp(rand.Int64N(int64(n)))
+	}
+	return 0
+}
+
+func (m *Map[K, V]) maxJitter(ttl timestamp) timestamp {
+	return timestamp(float64(ttl) * m.jitter)
+}
+
+// capRank limits rank to the maximum age of the item.
+func (m *Map[K, V]) capRank(item Item[K, V], rank timestamp) timestamp {
+	if m.maxAge != 0 {
+		rank = min(rank, item.entry().created+m.maxAge)
+	}
+	return rank
+}
+
+// setTTL changes the time-to-live of the item; the rank is moved in both directions when it falls outside
+// of the jitter and accuracy window of the new ttl.
+func (m *Map[K, V]) setTTL(item Item[K, V], ttl timestamp, now timestamp) {
+	item.entry().ttl = ttl
+	upper := m.capRank(item, now+ttl+m.maxJitter(ttl)+m.accuracyH)
+	if item.Rank().Before(m.capRank(item, now+ttl)) || upper.Before(item.Rank()) {
+		rank := m.capRank(item, now+ttl+m.jitterOf(ttl)+m.accuracyH)
+		m.m.SetRank(item.MapItem, rank)
+		m.checkTimer(rank+m.accuracyH, now)
+	}
+}
+
+// checkTimer ensures that the timer fires no later than deadline.
+func (m *Map[K, V]) checkTimer(deadline timestamp, now timestamp) {
+	if m.manual || m.paused {
+		return
+	}
+	if m.timer == nil {
+		m.startTimer(now)
+	} else if deadline.Before(m.deadline) && m.timer.Stop() {
+		// if the timer cannot be stopped, the cleanup is already pending and it restarts the timer
+		m.startTimer(now)
+	}
+}
+
+// restartTimer is called at the end of the cleanup.
+func (m *Map[K, V]) restartTimer(now timestamp) {
+	if m.manual {
+		return
+	}
+	if !m.paused && (m.Len() > 0 || m.evicted.Len() > 0) {
+		m.startTimer(now)
+	} else {
+		m.timer = nil // mark the timer as stopped
 	}
 }
 
 func (m *Map[K, V]) startTimer(now timestamp) {
-	delay := m.m.First().Rank() - now + m.accuracyH
-	m.timer = time.AfterFunc(toDuration(delay), m.queueCleanup)
+	if m.evicted.Len() > 0 || m.flushing {
+		m.deadline = now
+	} else {
+		m.deadline = m.dueDeadline()
+	}
+	m.timer = m.clock.AfterFunc(toDuration(m.deadline-now), m.queueCleanup)
+}
+
+// rescheduleTimer moves the deadline of a running timer after the ranks or the accuracy have changed.
+func (m *Map[K, V]) rescheduleTimer(now timestamp) {
+	// if the timer cannot be stopped, the cleanup is already pending and it restarts the timer
+	if m.timer != nil && m.timer.Stop() {
+		m.restartTimer(now)
+	}
+}
+
+// dueDeadline returns when the first items have to be removed.
+func (m *Map[K, V]) dueDeadline() timestamp {
+	if m.wheel {
+		// the slots of the wheel are accuracy/2 wide and they are due at their end
+		return m.m.DueRank()
+	}
+	return m.m.DueRank() + m.accuracyH
+}
+
+func (m *Map[K, V]) now() timestamp {
+	if m.paused {
+		return m.pausedAt
+	}
+	return fromTime(m.clock.Now())
+}
+
+func checkParams(ttl, accuracy time.Duration) {
+	if ttl < time.Millisecond {
+		panic(fmt.Errorf("ttlmap: invalid time-to-live: %v", ttl))
+	}
+	if accuracy >= ttl || accuracy < 0 {
+		panic(fmt.Errorf("ttlmap: invalid accuracy %v for ttl %v", accuracy, ttl))
+	}
+}
+
+func checkWheelAccuracy(accuracy time.Duration) {
+	if accuracy < 2 {
+		panic(fmt.Errorf("ttlmap: the timing wheel requires a non-zero accuracy"))
+	}
+}
+
+func itemTTL(ttl time.Duration) timestamp {
+	if ttl < time.Millisecond {
+		panic(fmt.Errorf("ttlmap: invalid time-to-live: %v", ttl))
+	}
+	return fromDuration(ttl)
 }
 
 /*
@@ -174,6 +732,32 @@ func (m *Map[K, V]) startTimer(now timestamp) {
 	I3	ttl
 
 
+  Expire-after-write mode:
+  - Get, GetOrCreate and Touch do not refresh the rank; Set, SetWithTTL and the WithTTL methods do.
+  - the rules above are unchanged, so the lifetime range is from ttl to ttl+acc after the last write.
+
+
+  Maximum age:
+  - every rank is capped at created+maxAge, where created is the time of the last write.
+  - the timer fires at most acc/2 after the rank, so the lifetime is at most maxAge+acc/2 after the last write.
+
+
+  Jitter:
+  - a random amount up to jitter*ttl is added to the rank whenever it is set, so the lifetime range is from ttl to
+    ttl+jitter*ttl+acc; a refresh keeps the rank while it is at least now+ttl, as above.
+
+
+  Timing wheel:
+  - the ranks are the same, but the items are kept in slots acc/2 wide instead of a heap.
+  - a slot is due at its end, which is at most acc/2 after the rank of its items: the timer fires then, instead of
+    acc/2 after the first rank, so the lifetime range is still from ttl to ttl+acc.
+
+
+  Expiry policy:
+  - the lifetime returned by the policy is applied like the ttl of TouchWithTTL: it becomes the item ttl and the rank
+    is moved in both directions, so reads can also shorten the lifetime.
+
+
   Original design:
   - rank when inserting: now+ttl
   - rank range: from ttl-acc to ttl
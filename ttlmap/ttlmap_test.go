@@ -24,6 +24,14 @@ const (
 )
 
 func testCore(t *testing.T, numKeys uint16, ops []byte) {
+	testCoreWith(t, numKeys, ops)
+}
+
+func testCoreWheel(t *testing.T, numKeys uint16, ops []byte) {
+	testCoreWith(t, numKeys, ops, ttlmap.WithTimingWheel())
+}
+
+func testCoreWith(t *testing.T, numKeys uint16, ops []byte, opts ...ttlmap.Option) {
 	if numKeys < 1 || len(ops) < 1 {
 		return
 	}
@@ -43,7 +51,7 @@ func testCore(t *testing.T, numKeys uint16, ops []byte) {
 		log.Printf("ttl %v - accuracy %v", ttl, accuracy)
 
 		ref := make(map[int]time.Time)
-		m, expired := ttlmap.New[int, struct{}](ttl, accuracy)
+		m, expired := ttlmap.New[int, struct{}](ttl, accuracy, opts...)
 
 		storedItem := m.NullItem()
 		storedKey := -1
@@ -273,6 +281,16 @@ func (o *operations) toBytes() []byte {
 }
 
 func Fuzz_ItemsExpireAtTheRightTime(f *testing.F) {
+	addCoreSeeds(f)
+	f.Fuzz(testCore)
+}
+
+func Fuzz_ItemsExpireAtTheRightTimeWheel(f *testing.F) {
+	addCoreSeeds(f)
+	f.Fuzz(testCoreWheel)
+}
+
+func addCoreSeeds(f *testing.F) {
 	var ops operations
 	for delay := byte(20); delay <= 30; delay++ {
 		ops.append(opSet1, delay)
@@ -292,8 +310,6 @@ func Fuzz_ItemsExpireAtTheRightTime(f *testing.F) {
 	ops.append(opSet1, ttlTicks/2)
 	ops.append(opRemove, 0)
 	f.Add(uint16(1), ops.toBytes())
-
-	f.Fuzz(testCore)
 }
 
 func Test_Touch(t *testing.T) {
@@ -470,3 +486,69 @@ func Test_TouchExpired(t *testing.T) {
 		assert.Zero(t, m.Len())
 	})
 }
+
+func Test_ItemTTL(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		const ttl = time.Second
+		m, expired := ttlmap.New[int, int](ttl, 0)
+
+		waitAndAssertExpired := func(after time.Duration, x ...int) {
+			t0 := time.Now()
+			var exp []int
+			for item := range <-expired {
+				exp = append(exp, item.Key())
+			}
+			assert.ElementsMatch(t, x, exp)
+			assert.Equal(t, after, time.Since(t0))
+		}
+
+		m.Set(0, 0)
+		m.SetWithTTL(1, 1, 2*ttl)
+		m.SetWithTTL(2, 2, ttl/2)
+		_, found := m.GetOrCreateWithTTL(3, 3*ttl)
+		assert.False(t, found)
+		waitAndAssertExpired(ttl/2, 2)
+
+		// the item ttl survives touching and setting
+		m.Touch(m.GetNoTouch(1))
+		m.Set(1, 10)
+		assert.Equal(t, 10, *m.GetNoTouch(1).Value())
+		waitAndAssertExpired(ttl/2, 0)
+		waitAndAssertExpired(ttl*3/2, 1)
+
+		// the remaining lifetime can be shortened
+		_, found = m.GetOrCreateWithTTL(3, ttl/4)
+		assert.True(t, found)
+		waitAndAssertExpired(ttl/4, 3)
+
+		item := m.Set(4, 4)
+		m.TouchWithTTL(item, ttl/4)
+		waitAndAssertExpired(ttl/4, 4)
+		assert.Zero(t, m.Len())
+
+		assert.Panics(t, func() { m.SetWithTTL(5, 5, 0) })
+	})
+}
+
+func Test_ItemTTLAccuracy(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		const (
+			ttl      = time.Second
+			accuracy = ttl / 10
+		)
+		m, expired := ttlmap.New[int, time.Time](ttl, accuracy)
+
+		for i := range 20 {
+			m.SetWithTTL(i, time.Now(), ttl/2+time.Duration(i)*accuracy/3)
+			time.Sleep(accuracy / 7)
+		}
+		for m.Len() > 0 {
+			for item := range <-expired {
+				elapsed := time.Since(*item.Value())
+				itemTTL := ttl/2 + time.Duration(item.Key())*accuracy/3
+				assert.GreaterOrEqual(t, elapsed, itemTTL)
+				assert.LessOrEqual(t, elapsed, itemTTL+accuracy)
+			}
+		}
+	})
+}
@@ -0,0 +1,38 @@
+package ttlmap
+
+// Weigher returns the weight of an item, for example the approximate size of its value in bytes. It must not call
+// any method of the map.
+type Weigher[K comparable, V any] func(k K, v V) int64
+
+// Weight returns the total weight of the items in the map; it is always 0 unless the map was created with
+// WithMaxWeight.
+func (m *Map[K, V]) Weight() int64 {
+	return m.weight
+}
+
+// Updated must be called after changing the value of an item through Item.Value, including the items created by
+// GetOrCreate, so that it is handled as written by Set: its weight and its lifetime are computed again. The item
+// itself may be evicted if it is the closest to expiration and the map was created with WithMaxWeight.
+func (m *Map[K, V]) Updated(item Item[K, V]) {
+	m.checkOpen()
+	if item.Present() {
+		m.written(item, true, *item.Value(), m.now())
+		m.weigh(item)
+	}
+}
+
+// weigh updates the weight of the item and evicts items until the total weight is within the limit.
+func (m *Map[K, V]) weigh(item Item[K, V]) {
+	if m.weigher == nil {
+		return
+	}
+	e := item.entry()
+	w := m.weigher(item.Key(), e.value)
+	m.weight += w - e.weight
+	e.weight = w
+	if m.weight > m.maxWeight {
+		now := m.now()
+		for m.weight > m.maxWeight && m.m.Len() > 0 && m.evict(now) {
+		}
+	}
+}
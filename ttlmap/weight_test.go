@@ -0,0 +1,79 @@
+package ttlmap_test
+
+import (
+	"iter"
+	"testing"
+	"time"
+
+	"github.com/ddirect/container/ttlmap"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_MaxWeight(t *testing.T) {
+	const ttl = time.Second
+	c := ttlmap.NewManualClock(time.Unix(1000, 0))
+	var evicted []string
+	weigher := func(k string, v []byte) int64 { return int64(len(v)) }
+	m := ttlmap.NewAsync(ttl, 0, func(items iter.Seq[ttlmap.Item[string, []byte]]) {
+		for item := range items {
+			assert.True(t, item.Evicted())
+			evicted = append(evicted, item.Key())
+		}
+	}, ttlmap.WithClock(c), ttlmap.WithMaxWeight(10, weigher))
+
+	m.Set("a", make([]byte, 3))
+	c.Advance(ttl / 10)
+	m.Set("b", make([]byte, 3))
+	c.Advance(ttl / 10)
+	m.Set("c", make([]byte, 3))
+	assert.Equal(t, int64(9), m.Weight())
+
+	// replacing a value adjusts the total
+	c.Advance(ttl / 10)
+	m.Set("b", make([]byte, 1))
+	assert.Equal(t, int64(7), m.Weight())
+
+	c.Advance(ttl / 10)
+	m.Set("d", make([]byte, 6))
+	c.Advance(0)
+	assert.Equal(t, []string{"a"}, evicted)
+	assert.Equal(t, int64(10), m.Weight())
+
+	// values updated in place are weighed again by Updated
+	c.Advance(ttl / 10)
+	item, found := m.GetOrCreate("e")
+	assert.False(t, found)
+	*item.Value() = make([]byte, 4)
+	m.Updated(item)
+	c.Advance(0)
+	assert.Equal(t, []string{"a", "c", "b"}, evicted)
+	assert.Equal(t, int64(10), m.Weight())
+
+	m.DeleteKey("e")
+	assert.Equal(t, int64(6), m.Weight())
+
+	// an item heavier than the limit does not stay in the map
+	c.Advance(ttl / 10)
+	m.Set("f", make([]byte, 11))
+	assert.False(t, m.Exists("f"))
+	assert.Zero(t, m.Weight())
+	m.Close(false)
+}
+
+func Test_MaxWeightSync(t *testing.T) {
+	c := ttlmap.NewManualClock(time.Unix(1000, 0))
+	var evicted []int
+	m := ttlmap.NewSync(time.Second, 0, func(items iter.Seq2[int, int]) {
+		for k := range items {
+			evicted = append(evicted, k)
+		}
+	}, ttlmap.WithClock(c), ttlmap.WithMaxWeight(10, func(k, v int) int64 { return int64(v) }))
+
+	m.GetOrCreate(0, func() int { return 6 })
+	c.Advance(time.Millisecond)
+	m.GetOrCreate(1, func() int { return 6 })
+	c.Advance(0)
+	assert.Equal(t, []int{0}, evicted)
+	assert.Equal(t, int64(6), m.Weight())
+	m.Close(false)
+}
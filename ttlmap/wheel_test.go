@@ -0,0 +1,59 @@
+package ttlmap_test
+
+import (
+	"iter"
+	"math/rand/v2"
+	"testing"
+	"time"
+
+	"github.com/ddirect/container/ttlmap"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_TimingWheel(t *testing.T) {
+	const (
+		ttl      = time.Second
+		accuracy = ttl / 10
+	)
+	c := ttlmap.NewManualClock(time.Unix(1000, 0))
+	rnd := rand.New(rand.NewPCG(1, 0))
+	lastAccess := make(map[int]time.Time)
+	var m *ttlmap.Map[int, int]
+	m = ttlmap.NewAsync(ttl, accuracy, func(items iter.Seq[ttlmap.Item[int, int]]) {
+		for item := range items {
+			// resurrect some of the items
+			if rnd.IntN(10) == 0 {
+				m.Touch(item)
+				lastAccess[item.Key()] = c.Now()
+				continue
+			}
+			lifetime := c.Now().Sub(lastAccess[item.Key()])
+			assert.GreaterOrEqual(t, lifetime, ttl)
+			assert.LessOrEqual(t, lifetime, ttl+accuracy)
+			delete(lastAccess, item.Key())
+		}
+	}, ttlmap.WithClock(c), ttlmap.WithTimingWheel(), ttlmap.WithStats())
+
+	for range 10_000 {
+		k := rnd.IntN(1000)
+		if rnd.IntN(2) == 0 {
+			m.Set(k, k)
+			lastAccess[k] = c.Now()
+		} else if m.Get(k).Present() {
+			lastAccess[k] = c.Now()
+		}
+		c.Advance(time.Duration(rnd.IntN(int(ttl / 100))))
+	}
+	assert.Equal(t, len(lastAccess), m.Len())
+	c.Advance(2 * ttl)
+	for c.AdvanceToNext() {
+	}
+	assert.Zero(t, m.Len())
+	assert.Empty(t, lastAccess)
+	assert.Zero(t, c.Pending())
+	m.Close(false)
+}
+
+func Test_TimingWheelInvalidAccuracy(t *testing.T) {
+	assert.Panics(t, func() { ttlmap.NewManual[int, int](time.Second, 0, ttlmap.WithTimingWheel()) })
+}
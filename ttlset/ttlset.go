@@ -0,0 +1,90 @@
+package ttlset
+
+import (
+	"iter"
+	"time"
+
+	"github.com/ddirect/container/internal/notifier"
+	"github.com/ddirect/container/ttlmap"
+)
+
+// ttlset.Set is a set where unused keys are automatically removed when they expire. It is built on ttlmap.Map and
+// shares its timer and accuracy semantics. It is not safe to call any method concurrently from different goroutines.
+// This includes iterating on the expired keys sequence.
+type Set[K comparable] struct {
+	m *ttlmap.Map[K, struct{}]
+}
+
+// New creates a new Set and the channel where the expired keys are received; the parameters have the same meaning as
+// in ttlmap.New. Iterating through the keys is required in order for them to be removed from the set. The channel is
+// closed by Close.
+func New[K comparable](ttl, accuracy time.Duration, opts ...ttlmap.Option) (*Set[K], <-chan iter.Seq[K]) {
+	expired := notifier.New[iter.Seq[K]]()
+	opts = append(opts[:len(opts):len(opts)], ttlmap.WithCloseListener(expired.Close))
+	return NewAsync(ttl, accuracy, expired.Send, opts...), expired.C()
+}
+
+// NewAsync is like New, but instead of returning a channel, it gets a method which is called when keys expire.
+func NewAsync[K comparable](ttl, accuracy time.Duration, handleExpired func(iter.Seq[K]), opts ...ttlmap.Option) *Set[K] {
+	return &Set[K]{
+		m: ttlmap.NewAsync(ttl, accuracy, func(items iter.Seq[ttlmap.Item[K, struct{}]]) {
+			handleExpired(func(yield func(K) bool) {
+				for item := range items {
+					if !yield(item.Key()) {
+						return
+					}
+				}
+			})
+		}, opts...),
+	}
+}
+
+func (s *Set[K]) Len() int {
+	return s.m.Len()
+}
+
+// Add inserts k or refreshes its lifetime if it is already present; it returns true if k was not present.
+func (s *Set[K]) Add(k K) bool {
+	added := !s.m.Exists(k)
+	s.m.Set(k, struct{}{})
+	return added
+}
+
+// Contains reports whether k is present, refreshing its lifetime if it is.
+func (s *Set[K]) Contains(k K) bool {
+	return s.m.Get(k).Present()
+}
+
+// ContainsNoTouch is like Contains, but it does not refresh the lifetime of k.
+func (s *Set[K]) ContainsNoTouch(k K) bool {
+	return s.m.Exists(k)
+}
+
+// Remove deletes k; it returns true if k was present.
+func (s *Set[K]) Remove(k K) bool {
+	return s.m.DeleteKey(k)
+}
+
+func (s *Set[K]) Clear() {
+	s.m.Clear()
+}
+
+// All returns the keys of the set in no particular order.
+func (s *Set[K]) All() iter.Seq[K] {
+	return func(yield func(K) bool) {
+		for item := range s.m.All() {
+			if !yield(item.Key()) {
+				return
+			}
+		}
+	}
+}
+
+// Close works like ttlmap.Map.Close.
+func (s *Set[K]) Close(flush bool) {
+	s.m.Close(flush)
+}
+
+func (s *Set[K]) Stats() ttlmap.Stats {
+	return s.m.Stats()
+}
@@ -0,0 +1,63 @@
+package ttlset_test
+
+import (
+	"iter"
+	"slices"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	"github.com/ddirect/container/ttlmap"
+	"github.com/ddirect/container/ttlset"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Set(t *testing.T) {
+	const ttl = time.Second
+	c := ttlmap.NewManualClock(time.Unix(1000, 0))
+	var expired []int
+	s := ttlset.NewAsync(ttl, 0, func(keys iter.Seq[int]) {
+		expired = append(expired, slices.Collect(keys)...)
+	}, ttlmap.WithClock(c))
+
+	assert.True(t, s.Add(0))
+	assert.True(t, s.Add(1))
+	assert.True(t, s.Add(2))
+	assert.False(t, s.Add(2))
+	assert.Equal(t, 3, s.Len())
+	assert.ElementsMatch(t, []int{0, 1, 2}, slices.Collect(s.All()))
+
+	c.Advance(ttl / 2)
+	assert.True(t, s.Contains(0))
+	assert.True(t, s.ContainsNoTouch(1))
+	assert.False(t, s.Contains(3))
+	assert.True(t, s.Remove(2))
+	assert.False(t, s.Remove(2))
+
+	c.Advance(ttl / 2)
+	assert.Equal(t, []int{1}, expired)
+	assert.True(t, s.ContainsNoTouch(0))
+	assert.False(t, s.ContainsNoTouch(1))
+
+	c.Advance(ttl / 2)
+	assert.Equal(t, []int{1, 0}, expired)
+	assert.Zero(t, s.Len())
+	s.Close(false)
+}
+
+func Test_SetChannel(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		const ttl = time.Second
+		s, expired := ttlset.New[string](ttl, 0)
+		s.Add("a")
+		s.Add("b")
+		assert.ElementsMatch(t, []string{"a", "b"}, slices.Collect(<-expired))
+		assert.Zero(t, s.Len())
+
+		s.Add("c")
+		s.Close(true)
+		assert.Equal(t, []string{"c"}, slices.Collect(<-expired))
+		_, ok := <-expired
+		assert.False(t, ok)
+	})
+}